@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"path"
 	"sherry/shr/config"
+	"sherry/shr/constants"
 	"sherry/shr/helpers"
 	"strings"
 )
@@ -24,22 +25,34 @@ type ErrorResponseArray = struct {
 	StatusCode int      `json:"statusCode"`
 }
 
-func getUrl(route string) string {
-	base, err := url.Parse(config.GetConfig().ApiUrl)
-	if err != nil {
-		helpers.PrintErr("Can't parse API URL")
-		return ""
+func getUrl(route string) (string, error) {
+	apiUrl := config.GetConfig().ApiUrl
+	base, err := url.Parse(apiUrl)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", helpers.UsageError("Can't parse API URL \"%s\" of the configuration", apiUrl)
 	}
 	parts := strings.SplitN(route, "?", 2)
 	base.Path = path.Join(base.Path, parts[0])
 	if len(parts) == 2 {
 		base.RawQuery = parts[1]
 	}
-	return base.String()
+	return base.String(), nil
 }
 
 var UnsuccessfulResponseCodeError = errors.New("unsuccessful response code")
 
+type StatusCodeError struct {
+	StatusCode int
+}
+
+func (e *StatusCodeError) Error() string {
+	return fmt.Sprintf("%s: %d", UnsuccessfulResponseCodeError, e.StatusCode)
+}
+
+func (e *StatusCodeError) Unwrap() error {
+	return UnsuccessfulResponseCodeError
+}
+
 func isSuccess(res *http.Response) bool {
 	switch res.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
@@ -55,30 +68,73 @@ func parse(res *http.Response) (string, error) {
 	}
 	str := string(body)
 	if !isSuccess(res) {
-		return str, UnsuccessfulResponseCodeError
+		return str, &StatusCodeError{StatusCode: res.StatusCode}
 	}
 	return str, nil
 }
 
-func authRequest(method string, route string, body *bytes.Buffer, auth string) (string, error) {
+func newRequest(method string, route string, body []byte, auth string) (*http.Request, error) {
+	u, err := getUrl(route)
+	if err != nil {
+		return nil, err
+	}
 	var req *http.Request
-	var err error
 	if body == nil {
-		req, err = http.NewRequest(method, getUrl(route), nil)
+		req, err = http.NewRequest(method, u, nil)
 	} else {
-		req, err = http.NewRequest(method, getUrl(route), body)
+		req, err = http.NewRequest(method, u, bytes.NewReader(body))
 	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setAuthorization(req, auth)
+	return req, nil
+}
+
+func setAuthorization(req *http.Request, auth string) {
 	if auth != "" {
 		req.Header.Set("Authorization", fmt.Sprint("Bearer ", auth))
 	}
-	res, err := http.DefaultClient.Do(req)
+}
+
+// send performs the request built by build and transparently refreshes the session
+// of the user owning auth when the token is known to be expired or the server rejects it.
+// build may be called twice, so it must not reuse a consumed request body.
+func send(auth string, build func(auth string) (*http.Request, error)) (*http.Response, error) {
+	auth, err := prepareToken(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	for refreshes := 0; ; refreshes++ {
+		req, err := build(auth)
+		if err != nil {
+			return nil, err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if auth == "" || res.StatusCode != http.StatusUnauthorized || refreshes == maxSessionRefreshes {
+			return res, nil
+		}
+		_ = res.Body.Close()
+
+		if auth, err = refreshToken(auth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func authRequest(method string, route string, body []byte, auth string) (string, error) {
+	res, err := send(auth, func(auth string) (*http.Request, error) {
+		return newRequest(method, route, body, auth)
+	})
 	if err != nil {
 		return "", err
 	}
+	defer res.Body.Close()
 	return parse(res)
 }
 
@@ -91,46 +147,77 @@ func Delete(route string, auth string) (string, error) {
 }
 
 func Post(route string, body []byte, auth string) (string, error) {
-	return authRequest(http.MethodPost, route, bytes.NewBuffer(body), auth)
+	return authRequest(http.MethodPost, route, body, auth)
 }
 
 func Patch(route string, body []byte, auth string) (string, error) {
-	return authRequest(http.MethodPatch, route, bytes.NewBuffer(body), auth)
+	return authRequest(http.MethodPatch, route, body, auth)
+}
+
+func getStatusExitCode(status int) int {
+	switch {
+	case status == http.StatusUnauthorized:
+		return constants.ExitAuthExpired
+	case status == http.StatusNotFound:
+		return constants.ExitNotFound
+	case status == http.StatusConflict:
+		return constants.ExitConflict
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return constants.ExitUsage
+	case status >= http.StatusInternalServerError:
+		return constants.ExitNetwork
+	}
+	return constants.ExitFailure
+}
+
+// classifyError attaches an exit code to errors that did not come from a server response
+func classifyError(err error) error {
+	var commandError *helpers.CommandError
+	if errors.As(err, &commandError) {
+		return err
+	}
+	if errors.Is(err, SessionExpiredError) {
+		return helpers.WrapError(constants.ExitAuthExpired, err, "Your session has expired, please login again")
+	}
+	var statusError *StatusCodeError
+	if errors.As(err, &statusError) {
+		return helpers.WrapError(getStatusExitCode(statusError.StatusCode), err, "")
+	}
+	return helpers.NetworkError(err)
+}
+
+func getResponseMessage(res string) string {
+	var resErr ErrorResponse
+	if json.Unmarshal([]byte(res), &resErr) == nil {
+		return resErr.Message
+	}
+	var resErrArr ErrorResponseArray
+	if json.Unmarshal([]byte(res), &resErrArr) != nil {
+		return res
+	}
+	lines := []string{"Couple errors found:"}
+	for _, m := range resErrArr.Message {
+		lines = append(lines, fmt.Sprintf("  %s", m))
+	}
+	return strings.Join(lines, "\n")
 }
 
 func ValidateResponse(res string, err error) (string, error) {
-	if err != nil {
-		if res != "" {
-			var resErr ErrorResponse
-			if json.Unmarshal([]byte(res), &resErr) == nil {
-				helpers.PrintErr(resErr.Message)
-			} else {
-				var resErrArr ErrorResponseArray
-				err := json.Unmarshal([]byte(res), &resErrArr)
-				if err != nil {
-					helpers.PrintErr(res)
-				} else {
-					helpers.PrintErr("Couple errors found:")
-					for _, m := range resErrArr.Message {
-						helpers.PrintErr(fmt.Sprintf("  %s", m))
-					}
-				}
-			}
-
-		} else {
-			helpers.PrintErr(err.Error())
-		}
-		return "", err
+	if err == nil {
+		return res, nil
 	}
-	return res, nil
+
+	var statusError *StatusCodeError
+	if res != "" && errors.As(err, &statusError) {
+		return "", helpers.WrapError(getStatusExitCode(statusError.StatusCode), err, getResponseMessage(res))
+	}
+	return "", classifyError(err)
 }
 
 func ParseResponse[T any](res string) (*T, error) {
 	var v T
 	if err := json.Unmarshal([]byte(res), &v); err != nil {
-		helpers.PrintErr("Can't parse response")
-		helpers.PrintErr(err.Error())
-		return nil, err
+		return nil, helpers.WrapError(constants.ExitFailure, err, fmt.Sprintf("Can't parse response: %s", err))
 	}
 	return &v, nil
 }
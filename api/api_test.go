@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "Test unauthorized", err: &StatusCodeError{StatusCode: http.StatusUnauthorized}, want: constants.ExitAuthExpired},
+		{name: "Test forbidden", err: &StatusCodeError{StatusCode: http.StatusForbidden}, want: constants.ExitFailure},
+		{name: "Test not found", err: &StatusCodeError{StatusCode: http.StatusNotFound}, want: constants.ExitNotFound},
+		{name: "Test conflict", err: &StatusCodeError{StatusCode: http.StatusConflict}, want: constants.ExitConflict},
+		{name: "Test bad request", err: &StatusCodeError{StatusCode: http.StatusBadRequest}, want: constants.ExitUsage},
+		{name: "Test unprocessable entity", err: &StatusCodeError{StatusCode: http.StatusUnprocessableEntity}, want: constants.ExitUsage},
+		{name: "Test server error", err: &StatusCodeError{StatusCode: http.StatusInternalServerError}, want: constants.ExitNetwork},
+		{name: "Test bad gateway", err: &StatusCodeError{StatusCode: http.StatusBadGateway}, want: constants.ExitNetwork},
+		{name: "Test session expired", err: SessionExpiredError, want: constants.ExitAuthExpired},
+		{name: "Test connection error", err: errors.New("connection refused"), want: constants.ExitNetwork},
+		{name: "Test command error", err: helpers.UsageError("bad value"), want: constants.ExitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, helpers.GetExitCode(classifyError(tt.err)))
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	_, err := ValidateResponse(`{"message":"Folder not found","statusCode":404}`, &StatusCodeError{StatusCode: http.StatusNotFound})
+	assert.Equal(t, constants.ExitNotFound, helpers.GetExitCode(err))
+	assert.EqualError(t, err, "Folder not found")
+}
+
+func TestInvalidApiUrl(t *testing.T) {
+	for _, apiUrl := range []string{"", "localhost:3000", "http://[::1"} {
+		t.Run(apiUrl, func(t *testing.T) {
+			config.SetConfig(&config.Config{ApiUrl: apiUrl})
+			config.SetAuthConfig(&config.AuthorizationConfig{Sources: map[string]config.Credentials{}})
+			_, err := UserGet("token")
+			assert.Equal(t, constants.ExitUsage, helpers.GetExitCode(err))
+		})
+	}
+}
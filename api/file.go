@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+)
+
+type PayloadFileMove = struct {
+	OldPath string `json:"oldPath"`
+	Path    string `json:"path"`
+}
+
+// FolderFileUpload streams the local file src to filePath of the folder as multipart form
+func FolderFileUpload(id, filePath string, src string, hash string, size uint64, accessToken string) (*FileResponse, error) {
+	res, err := send(accessToken, func(auth string) (*http.Request, error) {
+		u, err := getUrl(fmt.Sprintf("/file/instance/%s", id))
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+
+		body, writer := io.Pipe()
+		form := multipart.NewWriter(writer)
+		go func() {
+			defer file.Close()
+			_ = form.WriteField("path", filePath)
+			_ = form.WriteField("hash", hash)
+			_ = form.WriteField("size", strconv.FormatUint(size, 10))
+			part, err := form.CreateFormFile("file", path.Base(filePath))
+			if err == nil {
+				_, err = io.Copy(part, file)
+			}
+			if err == nil {
+				err = form.Close()
+			}
+			_ = writer.CloseWithError(err)
+		}()
+
+		req, err := http.NewRequest(http.MethodPost, u, body)
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		setAuthorization(req, auth)
+		return req, nil
+	})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer res.Body.Close()
+
+	data, err := ValidateResponse(parse(res))
+	if err != nil {
+		return nil, err
+	}
+	return ParseResponse[FileResponse](data)
+}
+
+// FolderDirCreate creates the directory dirPath in the folder. The server has no documented directory route,
+// the upload route is assumed to accept a DIR entry without content like it lists them in FolderFiles.
+func FolderDirCreate(id, dirPath string, accessToken string) (*FileResponse, error) {
+	res, err := send(accessToken, func(auth string) (*http.Request, error) {
+		u, err := getUrl(fmt.Sprintf("/file/instance/%s", id))
+		if err != nil {
+			return nil, err
+		}
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		_ = form.WriteField("path", dirPath)
+		_ = form.WriteField("fileType", Dir)
+		_ = form.Close()
+
+		req, err := http.NewRequest(http.MethodPost, u, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		setAuthorization(req, auth)
+		return req, nil
+	})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer res.Body.Close()
+
+	data, err := ValidateResponse(parse(res))
+	if err != nil {
+		return nil, err
+	}
+	return ParseResponse[FileResponse](data)
+}
+
+// FolderFileDelete deletes the file or directory at filePath of the folder
+func FolderFileDelete(id, filePath string, accessToken string) error {
+	_, err := ValidateResponse(Delete(fileInstanceRoute(id, filePath), accessToken))
+	return err
+}
+
+// FolderFileMove renames the file, the response keeps the previous path in OldPath
+func FolderFileMove(id, oldPath, newPath string, accessToken string) (*FileResponse, error) {
+	body, _ := json.Marshal(PayloadFileMove{OldPath: oldPath, Path: newPath})
+	res, err := ValidateResponse(Patch(fmt.Sprintf("/file/instance/%s", id), body, accessToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseResponse[FileResponse](res)
+}
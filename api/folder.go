@@ -2,10 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"time"
 )
 
 type PayloadFolder = struct {
@@ -87,6 +93,11 @@ type FileResponse struct {
 	FileType     FileType `json:"fileType"`
 }
 
+// UpdatedTime returns the server modification time, timestamps are in milliseconds
+func (f FileResponse) UpdatedTime() time.Time {
+	return time.UnixMilli(int64(f.UpdatedAt))
+}
+
 func FolderCreate(payload PayloadFolder, accessToken string) (*ResponseFolder, error) {
 	body, _ := json.Marshal(payload)
 	res, err := ValidateResponse(Post("/sherry", body, accessToken))
@@ -144,6 +155,14 @@ func FolderPermission(folderId, userId string, payload PayloadFolderPermission,
 	return nil
 }
 
+// FolderPermissionRevoke refuses the access of the user to the folder
+func FolderPermissionRevoke(folderId, userId string, accessToken string) error {
+	return FolderPermission(folderId, userId, PayloadFolderPermission{
+		Action: PermissionActionRefuse,
+		Role:   PermissionRoleOwner, // Required by api, but will be ignored
+	}, accessToken)
+}
+
 func FolderFiles(id string, accessToken string) (*[]FileResponse, error) {
 	res, err := ValidateResponse(Get(fmt.Sprintf("/file/%s", id), accessToken))
 	if err != nil {
@@ -153,31 +172,78 @@ func FolderFiles(id string, accessToken string) (*[]FileResponse, error) {
 	return ParseResponse[[]FileResponse](res)
 }
 
-func FolderFileDownload(id, filePath string, accessToken string, dst string) error {
-	req, err := http.NewRequest(http.MethodGet, getUrl(fmt.Sprintf("/file/instance/%s?path=%s", id, filePath)), nil)
+var ChecksumMismatchError = errors.New("downloaded file does not match the server checksum")
+
+// FolderFileDownload downloads the file into a temporary file next to dst, verifies its size and hash
+// and then renames it to dst, so dst is never left partially written.
+// An empty hash skips the verification, received bytes are also copied to progress if it is set.
+func FolderFileDownload(id, filePath string, hash string, size uint64, accessToken string, dst string, progress io.Writer) error {
+	res, err := send(accessToken, func(auth string) (*http.Request, error) {
+		u, err := getUrl(fileInstanceRoute(id, filePath))
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		setAuthorization(req, auth)
+		return req, nil
+	})
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
+	defer res.Body.Close()
 
-	// Set the Authorization header
-	req.Header.Set("Authorization", fmt.Sprint("Bearer ", accessToken))
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
+	if !isSuccess(res) {
+		_, err = ValidateResponse(parse(res))
 		return err
 	}
 
-	out, err := os.Create(dst)
+	out, err := os.CreateTemp(filepath.Dir(dst), fmt.Sprintf(".%s.*.download", filepath.Base(dst)))
 	if err != nil {
-		return err
+		return helpers.FailureError("Can't create %s: %s", dst, err)
 	}
-	defer out.Close()
+	tmp := out.Name()
+	defer os.Remove(tmp)
 
-	_, err = io.Copy(out, res.Body)
+	h := helpers.NewHash()
+	writers := []io.Writer{out, h}
+	if progress != nil {
+		writers = append(writers, progress)
+	}
+	written, err := io.Copy(io.MultiWriter(writers...), res.Body)
+	closeErr := out.Close()
 	if err != nil {
-		return err
+		return helpers.NetworkError(err)
 	}
-	defer res.Body.Close()
+	if closeErr != nil {
+		return helpers.FailureError("Can't write %s: %s", dst, closeErr)
+	}
+
+	if hash != "" {
+		if uint64(written) != size {
+			return helpers.WrapError(constants.ExitNetwork, ChecksumMismatchError, fmt.Sprintf(
+				"Downloaded %s has %d bytes, expected %d", filePath, written, size,
+			))
+		}
+		if actual := helpers.HashSum(h); actual != hash {
+			return helpers.WrapError(constants.ExitNetwork, ChecksumMismatchError, fmt.Sprintf(
+				"Downloaded %s has hash %s, expected %s", filePath, actual, hash,
+			))
+		}
+	}
+
+	// Temporary files are created private, downloaded files get the usual permissions
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return helpers.FailureError("Can't write %s: %s", dst, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return helpers.FailureError("Can't move downloaded file to %s: %s", dst, err)
+	}
+	return nil
+}
 
-	return err
+func fileInstanceRoute(id string, filePath string) string {
+	return fmt.Sprintf("/file/instance/%s?path=%s", id, url.QueryEscape(filePath))
 }
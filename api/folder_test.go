@@ -0,0 +1,60 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"testing"
+)
+
+func TestFolderFileDownload(t *testing.T) {
+	content := "file content"
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name string
+		body string
+		hash string
+		size uint64
+		ok   bool
+	}{
+		{name: "Test valid file", body: content, hash: hash, size: uint64(len(content)), ok: true},
+		{name: "Test corrupted body", body: "file c0ntent", hash: hash, size: uint64(len(content))},
+		{name: "Test truncated body", body: content[:4], hash: hash, size: uint64(len(content))},
+		{name: "Test unknown hash", body: "file c0ntent", size: uint64(len(content)), ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/file/instance/folder-1", r.URL.Path)
+				assert.Equal(t, "docs/a.txt", r.URL.Query().Get("path"))
+				_, _ = w.Write([]byte(tt.body))
+			}, config.Credentials{UserId: "u-1", AccessToken: "token"})
+
+			dir := t.TempDir()
+			dst := filepath.Join(dir, "a.txt")
+			err := FolderFileDownload("folder-1", "docs/a.txt", tt.hash, tt.size, "token", dst, nil)
+
+			entries, _ := os.ReadDir(dir)
+			if tt.ok {
+				assert.NoError(t, err)
+				data, _ := os.ReadFile(dst)
+				assert.Equal(t, tt.body, string(data))
+				assert.Len(t, entries, 1)
+				return
+			}
+			assert.True(t, errors.Is(err, ChecksumMismatchError))
+			assert.Equal(t, constants.ExitNetwork, helpers.GetExitCode(err))
+			// Neither the destination nor the temporary file is left behind
+			assert.Empty(t, entries)
+		})
+	}
+}
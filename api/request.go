@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"strings"
+)
+
+// AccessRequestUnsupportedError is returned by servers without the access request routes,
+// the routes below are not part of the documented server API yet
+var AccessRequestUnsupportedError = errors.New("access requests are not supported by the server")
+
+type PayloadAccessRequest = struct {
+	OwnerId string                      `json:"ownerId"`
+	Name    string                      `json:"name"`
+	Role    PayloadFolderPermissionRole `json:"role"`
+}
+
+type ResponseAccessRequest = struct {
+	AccessRequestId string `json:"accessRequestId"`
+	SherryId        string `json:"sherryId"`
+	UserId          string `json:"userId"`
+	Role            string `json:"role"`
+	CreatedAt       uint64 `json:"createdAt"`
+}
+
+// AccessRequestCreate asks the owner of the folder for access, the folder is addressed by owner and name
+// because the requester can't see it yet
+func AccessRequestCreate(payload PayloadAccessRequest, accessToken string) (*ResponseAccessRequest, error) {
+	body, _ := json.Marshal(payload)
+	res, err := validateAccessRequestResponse(Post("/sherry/request", body, accessToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseResponse[ResponseAccessRequest](res)
+}
+
+// AccessRequestGetPending returns pending requests to folders owned by the user
+func AccessRequestGetPending(accessToken string) (*[]ResponseAccessRequest, error) {
+	res, err := validateAccessRequestResponse(Get("/sherry/request/pending", accessToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseResponse[[]ResponseAccessRequest](res)
+}
+
+// AccessRequestResolve grants the role to the requester or refuses the request
+func AccessRequestResolve(id string, payload PayloadFolderPermission, accessToken string) error {
+	body, _ := json.Marshal(payload)
+	_, err := validateAccessRequestResponse(Patch(fmt.Sprintf("/sherry/request/%s", id), body, accessToken))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateAccessRequestResponse reports a 405 or the framework's "Cannot <METHOD> <path>" 404
+// as unsupported, a 404 with a message of the server still means a missing folder or request
+func validateAccessRequestResponse(res string, err error) (string, error) {
+	var statusError *StatusCodeError
+	if errors.As(err, &statusError) {
+		unknownRoute := statusError.StatusCode == http.StatusNotFound && strings.HasPrefix(getResponseMessage(res), "Cannot ")
+		if statusError.StatusCode == http.StatusMethodNotAllowed || unknownRoute {
+			return "", helpers.WrapError(constants.ExitFailure, AccessRequestUnsupportedError,
+				"The server does not support access requests, ask the owner to grant access with shr folder permission grant")
+		}
+	}
+	return ValidateResponse(res, err)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"testing"
+)
+
+func TestAccessRequestFlow(t *testing.T) {
+	pending := []ResponseAccessRequest{
+		{AccessRequestId: "r-1", SherryId: "s-1", UserId: "u-2", Role: "READ"},
+		{AccessRequestId: "r-2", SherryId: "s-1", UserId: "u-3", Role: "WRITE"},
+	}
+	resolved := map[string]PayloadFolderPermission{}
+	setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/sherry/request":
+			var payload PayloadAccessRequest
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			assert.Equal(t, PayloadAccessRequest{OwnerId: "u-1", Name: "docs", Role: "READ"}, payload)
+			_ = json.NewEncoder(w).Encode(pending[0])
+		case r.Method == http.MethodGet && r.URL.Path == "/sherry/request/pending":
+			var open []ResponseAccessRequest
+			for _, p := range pending {
+				if _, ok := resolved[p.AccessRequestId]; !ok {
+					open = append(open, p)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(helpers.EmptyIfNull(open))
+		case r.Method == http.MethodPatch && r.URL.Path == "/sherry/request/r-1", r.Method == http.MethodPatch && r.URL.Path == "/sherry/request/r-2":
+			var payload PayloadFolderPermission
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			resolved[r.URL.Path[len("/sherry/request/"):]] = payload
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Request not found","statusCode":404}`))
+		}
+	}, config.Credentials{UserId: "u-1", AccessToken: "token"})
+
+	created, err := AccessRequestCreate(PayloadAccessRequest{OwnerId: "u-1", Name: "docs", Role: "READ"}, "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "r-1", created.AccessRequestId)
+
+	open, err := AccessRequestGetPending("token")
+	assert.NoError(t, err)
+	assert.Equal(t, pending, *open)
+
+	assert.NoError(t, AccessRequestResolve("r-1", PayloadFolderPermission{Role: "READ", Action: PermissionActionGrant}, "token"))
+	assert.NoError(t, AccessRequestResolve("r-2", PayloadFolderPermission{Role: "WRITE", Action: PermissionActionRefuse}, "token"))
+	assert.Equal(t, map[string]PayloadFolderPermission{
+		"r-1": {Role: "READ", Action: PermissionActionGrant},
+		"r-2": {Role: "WRITE", Action: PermissionActionRefuse},
+	}, resolved)
+
+	open, err = AccessRequestGetPending("token")
+	assert.NoError(t, err)
+	assert.Empty(t, *open)
+
+	err = AccessRequestResolve("r-3", PayloadFolderPermission{Role: "READ", Action: PermissionActionGrant}, "token")
+	assert.Equal(t, constants.ExitNotFound, helpers.GetExitCode(err))
+	assert.False(t, errors.Is(err, AccessRequestUnsupportedError))
+}
+
+func TestAccessRequestUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "Test unknown route", status: http.StatusNotFound, body: `{"message":"Cannot GET /sherry/request/pending","error":"Not Found","statusCode":404}`},
+		{name: "Test method not allowed", status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}, config.Credentials{UserId: "u-1", AccessToken: "token"})
+
+			_, err := AccessRequestGetPending("token")
+			assert.True(t, errors.Is(err, AccessRequestUnsupportedError))
+			assert.Equal(t, constants.ExitFailure, helpers.GetExitCode(err))
+			assert.ErrorContains(t, err, "shr folder permission grant")
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sherry/shr/config"
+	"sync"
+	"time"
+)
+
+var SessionExpiredError = errors.New("your session has expired, please login again")
+
+var sessionMutex sync.Mutex
+
+// maxSessionRefreshes bounds the refreshes of one request, a server rejecting a fresh token fails the request
+const maxSessionRefreshes = 1
+
+// refreshMargin refreshes the access token shortly before it expires, covering the request duration and clock skew
+const refreshMargin = 30 * time.Second
+
+// replacedTokens maps access tokens replaced by a refresh to the id of their user,
+// so callers holding a stale copy of the credentials keep working.
+var replacedTokens = map[string]string{}
+
+// findCredentials returns the current credentials of the user the token was issued to
+func findCredentials(token string) *config.Credentials {
+	sources := config.GetAuthConfig().Sources
+	if userId, ok := replacedTokens[token]; ok {
+		if c, ok := sources[userId]; ok {
+			return &c
+		}
+	}
+	for _, c := range sources {
+		if c.AccessToken == token {
+			return &c
+		}
+	}
+	return nil
+}
+
+func needsRefresh(credentials config.Credentials) bool {
+	if credentials.Expired {
+		return true
+	}
+	expiresAt, ok := credentials.ExpiresAt()
+	return ok && time.Now().Add(refreshMargin).After(expiresAt)
+}
+
+func prepareToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	sessionMutex.Lock()
+	credentials := findCredentials(token)
+	sessionMutex.Unlock()
+
+	if credentials == nil {
+		return token, nil
+	}
+	if !needsRefresh(*credentials) {
+		return credentials.AccessToken, nil
+	}
+	return refreshToken(credentials.AccessToken)
+}
+
+func refreshToken(token string) (string, error) {
+	sessionMutex.Lock()
+	defer sessionMutex.Unlock()
+
+	credentials := findCredentials(token)
+	if credentials == nil {
+		return "", SessionExpiredError
+	}
+	if credentials.AccessToken != token {
+		// Already refreshed by a concurrent request
+		return credentials.AccessToken, nil
+	}
+
+	refreshed, err := refreshCredentials(*credentials)
+	if err != nil {
+		return "", err
+	}
+	return refreshed.AccessToken, nil
+}
+
+func refreshCredentials(credentials config.Credentials) (*config.Credentials, error) {
+	authConfig := config.GetAuthConfig()
+
+	if credentials.RefreshToken == "" {
+		return nil, SessionExpiredError
+	}
+
+	body, _ := json.Marshal(PayloadRefresh{RefreshToken: credentials.RefreshToken})
+	// The refresh route is not part of the documented server api, it is assumed to sit next to auth/sign-in,
+	// take the refresh token and answer like sign-in. A server without it answers 404 and the error is returned.
+	res, err := Post("auth/refresh", body, "")
+	// Only a rejected refresh token ends the session, network and server errors keep it for the next attempt
+	var statusError *StatusCodeError
+	if errors.As(err, &statusError) && (statusError.StatusCode == http.StatusBadRequest || statusError.StatusCode == http.StatusUnauthorized) {
+		credentials.Expired = true
+		authConfig.Sources[credentials.UserId] = credentials
+		config.CommitAuth()
+		return nil, SessionExpiredError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ParseResponse[ResponseLogin](res)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.AccessToken != credentials.AccessToken {
+		replacedTokens[credentials.AccessToken] = credentials.UserId
+	}
+	credentials.AccessToken = response.AccessToken
+	if response.RefreshToken != "" {
+		credentials.RefreshToken = response.RefreshToken
+	}
+	credentials.ExpiresIn = response.ExpiresIn
+	credentials.IssuedAt = time.Now().Unix()
+	credentials.Expired = false
+	authConfig.Sources[credentials.UserId] = credentials
+	config.CommitAuth()
+
+	return &credentials, nil
+}
+
+// RefreshCredentials exchanges the stored refresh token of the user for a new access token
+// and saves the updated record to the authorization configuration.
+func RefreshCredentials(userId string) (*config.Credentials, error) {
+	sessionMutex.Lock()
+	defer sessionMutex.Unlock()
+
+	credentials, ok := config.GetAuthConfig().Sources[userId]
+	if !ok {
+		return nil, classifyError(SessionExpiredError)
+	}
+	refreshed, err := refreshCredentials(credentials)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return refreshed, nil
+}
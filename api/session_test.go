@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"testing"
+	"time"
+)
+
+// setupTestServer points the api at a test server and stores the credentials in a temporary config dir
+func setupTestServer(t *testing.T, handler http.HandlerFunc, credentials ...config.Credentials) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	auth := config.AuthorizationConfig{Sources: map[string]config.Credentials{}}
+	for _, c := range credentials {
+		auth.Sources[c.UserId] = c
+	}
+	dir := t.TempDir()
+	writeJson := func(name string, v interface{}) {
+		data, _ := json.Marshal(v)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+	writeJson(constants.ConfigFile, config.Config{ApiUrl: server.URL})
+	writeJson(constants.AuthConfigFile, auth)
+	assert.NoError(t, config.SetupConfig(dir))
+
+	replacedTokens = map[string]string{}
+}
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	var refreshes int
+	setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/refresh":
+			refreshes++
+			var payload PayloadRefresh
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			assert.Equal(t, "refresh", payload.RefreshToken)
+			_ = json.NewEncoder(w).Encode(ResponseLogin{UserId: "u-1", AccessToken: "fresh", RefreshToken: "refresh-2", ExpiresIn: 3600})
+		case "/user/me":
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(ResponseUser{UserId: "u-1", Username: "alice"})
+		}
+	}, config.Credentials{UserId: "u-1", AccessToken: "stale", RefreshToken: "refresh"})
+
+	user, err := UserGet("stale")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, 1, refreshes)
+
+	credentials := config.GetAuthConfig().Sources["u-1"]
+	assert.Equal(t, "fresh", credentials.AccessToken)
+	assert.Equal(t, "refresh-2", credentials.RefreshToken)
+	assert.False(t, credentials.Expired)
+
+	// A caller holding the replaced token gets the fresh one without another refresh
+	_, err = UserGet("stale")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, refreshes)
+}
+
+func TestRefreshBeforeExpiry(t *testing.T) {
+	var refreshes int
+	setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/refresh":
+			refreshes++
+			_ = json.NewEncoder(w).Encode(ResponseLogin{UserId: "u-1", AccessToken: "fresh", ExpiresIn: 3600})
+		case "/user/me":
+			assert.Equal(t, "Bearer fresh", r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(ResponseUser{UserId: "u-1"})
+		}
+	}, config.Credentials{
+		UserId:       "u-1",
+		AccessToken:  "expiring",
+		RefreshToken: "refresh",
+		ExpiresIn:    10,
+		IssuedAt:     time.Now().Unix(),
+	})
+
+	_, err := UserGet("expiring")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, refreshes)
+}
+
+func TestRefreshRejected(t *testing.T) {
+	setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"Unauthorized","statusCode":401}`))
+	}, config.Credentials{UserId: "u-1", AccessToken: "stale", RefreshToken: "refresh"})
+
+	_, err := UserGet("stale")
+	assert.Equal(t, constants.ExitAuthExpired, helpers.GetExitCode(err))
+	assert.True(t, config.GetAuthConfig().Sources["u-1"].Expired)
+}
+
+func TestRefreshServerError(t *testing.T) {
+	setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth/refresh" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}, config.Credentials{UserId: "u-1", AccessToken: "stale", RefreshToken: "refresh"})
+
+	_, err := UserGet("stale")
+	assert.Equal(t, constants.ExitNetwork, helpers.GetExitCode(err))
+	assert.False(t, config.GetAuthConfig().Sources["u-1"].Expired)
+}
@@ -31,6 +31,10 @@ type PayloadLogin = struct {
 	Password string `json:"password"`
 }
 
+type PayloadRefresh = struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 func UserRegister(payload PayloadUser) (*ResponseUser, error) {
 	body, _ := json.Marshal(payload)
 	res, err := ValidateResponse(Post("auth/sign-up", body, ""))
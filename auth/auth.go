@@ -5,6 +5,8 @@ import (
 	"sherry/shr/api"
 	"sherry/shr/config"
 	"sherry/shr/helpers"
+	"sort"
+	"time"
 )
 
 type SuccessRegistrationResponse = struct {
@@ -13,23 +15,63 @@ type SuccessRegistrationResponse = struct {
 	Username string `json:"username"`
 }
 
-func getUserInfo(register bool, email string, password string, user string) api.PayloadUser {
-	email = helpers.Input("Email", email, helpers.IsEmailValidator, "", false)
+type UserInfo = struct {
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Default  bool   `json:"default"`
+	Expired  bool   `json:"expired"`
+}
+
+func ToUserInfo(user config.Credentials) UserInfo {
+	return UserInfo{
+		UserId:   user.UserId,
+		Username: user.Username,
+		Email:    user.Email,
+		Default:  user.UserId == config.GetAuthConfig().Default,
+		Expired:  user.Expired,
+	}
+}
+
+func GetSortedUsers() []config.Credentials {
+	var users []config.Credentials
+	for _, u := range config.GetAuthConfig().Sources {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users
+}
+
+func getUserInfo(register bool, email string, password string, user string) (*api.PayloadUser, error) {
+	var err error
+	email, err = helpers.Input("Email", "--email", email, helpers.IsEmailValidator, "", false)
+	if err != nil {
+		return nil, err
+	}
 	if register {
-		user = helpers.Input("Username", user, helpers.IsWordValidator, "", false)
+		user, err = helpers.Input("Username", "--username", user, helpers.IsWordValidator, "", false)
+		if err != nil {
+			return nil, err
+		}
 	}
-	password = helpers.Input(
+	password, err = helpers.Input(
 		"Password",
+		"--password",
 		password,
 		helpers.IsPasswordValidator,
 		"At least 6 letters long, one Capital letter, one lowercase letter, and one number",
 		true,
 	)
-	return api.PayloadUser{
+	if err != nil {
+		return nil, err
+	}
+	return &api.PayloadUser{
 		Email:    email,
 		Username: user,
 		Password: password,
-	}
+	}, nil
 }
 
 func checkUserExists(email string, user string) bool {
@@ -47,29 +89,47 @@ func GetUserString(user config.Credentials) string {
 	return fmt.Sprintf("%s(%s)", user.Username, user.Email)
 }
 
-func RegisterUser(email string, password string, user string) bool {
-	info := getUserInfo(true, email, password, user)
+func RegisterUser(email string, password string, user string) (bool, error) {
+	info, err := getUserInfo(true, email, password, user)
+	if err != nil {
+		return false, err
+	}
 
 	if checkUserExists(info.Email, info.Username) {
-		helpers.PrintErr("User already authorized")
-		return false
+		return false, helpers.ConflictError("User already authorized")
 	}
 
 	helpers.PrintMap(info, "Credentials", []string{"password"})
 	helpers.PrintMessage("Creating user...")
 
-	createdUser, err := api.UserRegister(info)
+	createdUser, err := api.UserRegister(*info)
 	if err != nil {
-		return false
+		return false, err
 	}
 
 	helpers.PrintMessage("User created successfully")
 
-	return LoginUser(createdUser.Email, info.Password)
+	credentials, err := loginUser(createdUser.Email, info.Password)
+	if err == nil {
+		helpers.PrintResult(ToUserInfo(*credentials), func() {})
+	}
+	return credentials != nil, err
+}
+
+func LoginUser(email string, password string) (bool, error) {
+	credentials, err := loginUser(email, password)
+	if err == nil {
+		helpers.PrintResult(ToUserInfo(*credentials), func() {})
+	}
+	return credentials != nil, err
 }
 
-func LoginUser(email string, password string) bool {
-	info := getUserInfo(false, email, password, "")
+// loginUser saves the credentials of the user, they are also returned when setting the default user fails
+func loginUser(email string, password string) (*config.Credentials, error) {
+	info, err := getUserInfo(false, email, password, "")
+	if err != nil {
+		return nil, err
+	}
 
 	helpers.PrintMessage("Authorizing...")
 
@@ -78,28 +138,32 @@ func LoginUser(email string, password string) bool {
 		Password: info.Password,
 	})
 	if err != nil {
-		return false
+		return nil, err
 	}
 
 	authConfig := config.GetAuthConfig()
-	authConfig.Sources[authResponse.UserId] = config.Credentials{
+	credentials := config.Credentials{
 		UserId:       authResponse.UserId,
 		Email:        authResponse.Email,
 		Username:     authResponse.Username,
 		AccessToken:  authResponse.AccessToken,
 		RefreshToken: authResponse.RefreshToken,
 		ExpiresIn:    authResponse.ExpiresIn,
+		IssuedAt:     time.Now().Unix(),
 		Expired:      false,
 	}
+	authConfig.Sources[authResponse.UserId] = credentials
 
 	helpers.PrintMessage("User was successfully logged in")
 
 	if authConfig.Default == "" {
 		helpers.PrintMessage("It is the only user, setting it as default...")
-		SetDefaultUser(authResponse.Username)
+		if _, err := setDefaultUser(authResponse.Username); err != nil {
+			return &credentials, err
+		}
 	}
 
-	return true
+	return &credentials, nil
 }
 
 func FindUserByUsername(username string, withDefault bool) *config.Credentials {
@@ -118,6 +182,41 @@ func FindUserByUsername(username string, withDefault bool) *config.Credentials {
 	return nil
 }
 
+func activate(credentials *config.Credentials) (*config.Credentials, error) {
+	if !credentials.Expired {
+		return credentials, nil
+	}
+
+	// Errors carry their exit code, only a rejected refresh token is reported as expired session
+	return api.RefreshCredentials(credentials.UserId)
+}
+
+// FindActiveUser resolves the user the same way as FindUserByUsername with default fallback
+// and refreshes the session if it is marked as expired.
+func FindActiveUser(username string) (*config.Credentials, error) {
+	if username == "" {
+		credentials, _, err := FindContextUser()
+		if err != nil {
+			return nil, err
+		}
+		return activate(credentials)
+	}
+	credentials := FindUserByUsername(username, false)
+	if credentials == nil {
+		return nil, helpers.NotFoundError("User not found")
+	}
+	return activate(credentials)
+}
+
+// GetActiveUserById is FindActiveUser for a known user id, e.g. the user bound to a watcher
+func GetActiveUserById(userId string) (*config.Credentials, error) {
+	credentials := GetUserById(userId)
+	if credentials == nil {
+		return nil, helpers.NotFoundError("User %s is not authorized", userId)
+	}
+	return activate(credentials)
+}
+
 func GetUserById(userId string) *config.Credentials {
 	if v, ok := config.GetAuthConfig().Sources[userId]; ok {
 		return &v
@@ -125,56 +224,71 @@ func GetUserById(userId string) *config.Credentials {
 	return nil
 }
 
-func SetDefaultUser(user string) bool {
+func setDefaultUser(user string) (*config.Credentials, error) {
 	authConfig := config.GetAuthConfig()
 
 	var credentials = FindUserByUsername(user, false)
 
 	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
+		return nil, helpers.NotFoundError("User not found")
 	}
 
 	if authConfig.Default == credentials.UserId {
-		helpers.PrintErr("User is already default")
-		return false
+		return nil, helpers.ConflictError("User is already default")
 	}
 
 	authConfig.Default = credentials.UserId
 
 	helpers.PrintMessage(fmt.Sprintf("User %s set as default", GetUserString(*credentials)))
 
-	return true
+	return credentials, nil
 }
 
-func PrintDefaultUser() bool {
+func SetDefaultUser(user string) (bool, error) {
+	credentials, err := setDefaultUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	helpers.PrintResult(ToUserInfo(*credentials), func() {})
+
+	return true, nil
+}
+
+func PrintDefaultUser() (bool, error) {
 	authConfig := config.GetAuthConfig()
 	if authConfig.Default == "" {
-		helpers.PrintMessage("No default user set")
-		return false
+		helpers.PrintResult(nil, func() {
+			helpers.PrintMessage("No default user set")
+		})
+		return false, nil
 	}
 
 	for _, u := range authConfig.Sources {
 		if u.UserId == authConfig.Default {
-			helpers.PrintMessage(fmt.Sprintf("Default user: %s", GetUserString(u)))
-			return false
+			helpers.PrintResult(ToUserInfo(u), func() {
+				helpers.PrintMessage(fmt.Sprintf("Default user: %s", GetUserString(u)))
+			})
+			return false, nil
 		}
 	}
 
-	return false
+	return false, helpers.NotFoundError("Default user not found")
 }
 
-func PrintUsers() bool {
-	authConfig := config.GetAuthConfig()
-
-	helpers.PrintMessage("* - default user\n")
-	for _, u := range authConfig.Sources {
-		isDefault := " "
-		if u.UserId == authConfig.Default {
-			isDefault = "*"
+func PrintUsers() (bool, error) {
+	users := GetSortedUsers()
+
+	helpers.PrintResult(helpers.Map(users, ToUserInfo), func() {
+		helpers.PrintMessage("* - default user\n")
+		for _, u := range users {
+			isDefault := " "
+			if u.UserId == config.GetAuthConfig().Default {
+				isDefault = "*"
+			}
+			helpers.PrintMessage(fmt.Sprintf("%s %s", isDefault, GetUserString(u)))
 		}
-		helpers.PrintMessage(fmt.Sprintf("%s %s", isDefault, GetUserString(u)))
-	}
+	})
 
-	return false
+	return false, nil
 }
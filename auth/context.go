@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+)
+
+// Sources of the user of commands run without --user
+const (
+	ContextEnv     = "env"
+	ContextWatcher = "watcher"
+	ContextDefault = "default"
+)
+
+type ContextResult = struct {
+	User UserInfo `json:"user"`
+	// Source is one of ContextEnv, ContextWatcher or ContextDefault
+	Source string `json:"source"`
+	// LocalPath is the watched folder the user is bound to for ContextWatcher
+	LocalPath string `json:"localPath,omitempty"`
+}
+
+type UseResult = struct {
+	User    *UserInfo `json:"user"`
+	Env     string    `json:"env"`
+	Command string    `json:"command"`
+}
+
+// FindUser accepts a username or a user id
+func FindUser(user string) *config.Credentials {
+	if credentials := FindUserByUsername(user, false); credentials != nil {
+		return credentials
+	}
+	return GetUserById(user)
+}
+
+// FindContextUser resolves the user of commands run without --user: the user of SHERRY_USER,
+// then the user bound to the watched folder containing the working directory, then the default user
+func FindContextUser() (*config.Credentials, ContextResult, error) {
+	if env := os.Getenv(constants.EnvUser); env != "" {
+		credentials := FindUser(env)
+		if credentials == nil {
+			return nil, ContextResult{}, helpers.NotFoundError("User %s from %s not found", env, constants.EnvUser)
+		}
+		return credentials, ContextResult{User: ToUserInfo(*credentials), Source: ContextEnv}, nil
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		watcher, err := config.FindWatcher(helpers.PreparePath(wd))
+		if err == nil && watcher != nil {
+			if credentials := GetUserById(watcher.UserId); credentials != nil {
+				return credentials, ContextResult{User: ToUserInfo(*credentials), Source: ContextWatcher, LocalPath: watcher.LocalPath}, nil
+			}
+		}
+	}
+
+	credentials := FindUserByUsername("", true)
+	if credentials == nil {
+		return nil, ContextResult{}, helpers.NotFoundError("User not found")
+	}
+	return credentials, ContextResult{User: ToUserInfo(*credentials), Source: ContextDefault}, nil
+}
+
+func getEnvCommand(value string) string {
+	if runtime.GOOS == "windows" {
+		if value == "" {
+			return fmt.Sprintf("Remove-Item Env:%s", constants.EnvUser)
+		}
+		return fmt.Sprintf("$env:%s = \"%s\"", constants.EnvUser, value)
+	}
+	if value == "" {
+		return fmt.Sprintf("unset %s", constants.EnvUser)
+	}
+	return fmt.Sprintf("export %s=%s", constants.EnvUser, value)
+}
+
+func PrintContextUser() (bool, error) {
+	_, result, err := FindContextUser()
+	if err != nil {
+		return false, err
+	}
+
+	helpers.PrintResult(result, func() {
+		user := fmt.Sprintf("%s(%s)", result.User.Username, result.User.Email)
+		switch result.Source {
+		case ContextEnv:
+			helpers.PrintMessage(fmt.Sprintf("Using %s from %s", user, constants.EnvUser))
+		case ContextWatcher:
+			helpers.PrintMessage(fmt.Sprintf("Using %s bound to watched folder %s", user, result.LocalPath))
+		default:
+			helpers.PrintMessage(fmt.Sprintf("Using default user %s", user))
+		}
+	})
+
+	return false, nil
+}
+
+// UseUser prints the shell command selecting the user for the session, e.g. eval "$(shr auth use bob)",
+// unset prints the command returning to the default user
+func UseUser(user string, unset bool) (bool, error) {
+	result := UseResult{Env: constants.EnvUser}
+	if !unset {
+		credentials := FindUser(user)
+		if credentials == nil {
+			return false, helpers.NotFoundError("User %s not found", user)
+		}
+		info := ToUserInfo(*credentials)
+		result.User = &info
+		result.Command = getEnvCommand(credentials.Username)
+	} else {
+		result.Command = getEnvCommand("")
+	}
+
+	helpers.PrintResult(result, func() {
+		// Only the command is printed to stdout so the output can be evaluated by the shell
+		fmt.Println(result.Command)
+	})
+
+	return false, nil
+}
@@ -10,6 +10,14 @@ type Options struct {
 	Login    LoginOptions    `command:"login" description:"Authorize existing user"`
 	Default  DefaultOptions  `command:"default" description:"Display/Set default user"`
 	List     List            `command:"list" description:"List authorized users"`
+	Use      UseOptions      `command:"use" description:"Print shell command selecting the user for the session, or the user commands run as"`
+}
+
+type UseOptions struct {
+	Unset bool `long:"unset" description:"Print shell command returning to the default user"`
+	Args  struct {
+		Username string `positional-arg-name:"username" description:"Username or id, e.g. eval \"$(shr auth use bob)\""`
+	} `positional-args:"yes"`
 }
 
 type RegisterOptions struct {
@@ -32,12 +40,12 @@ type DefaultOptions struct {
 type List struct {
 }
 
-func ApplyCommand(cmd *flag.Command, data Options) {
+func ApplyCommand(cmd *flag.Command, data Options) error {
 	if cmd.Active.Name != "auth" {
-		return
+		return nil
 	}
 
-	config.WithCommit(func() bool {
+	return config.WithCommit(func() (bool, error) {
 		switch cmd.Active.Active.Name {
 		case "register":
 			return RegisterUser(data.Register.Email, data.Register.Password, data.Register.User)
@@ -45,6 +53,11 @@ func ApplyCommand(cmd *flag.Command, data Options) {
 			return LoginUser(data.Login.Email, data.Login.Password)
 		case "list":
 			return PrintUsers()
+		case "use":
+			if data.Use.Args.Username == "" && !data.Use.Unset {
+				return PrintContextUser()
+			}
+			return UseUser(data.Use.Args.Username, data.Use.Unset)
 		case "default":
 			var username = data.Default.Args.Username
 			if username == "" {
@@ -53,7 +66,7 @@ func ApplyCommand(cmd *flag.Command, data Options) {
 				return SetDefaultUser(username)
 			}
 		default:
-			return false
+			return false, nil
 		}
 	})
 }
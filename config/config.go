@@ -2,12 +2,12 @@ package config
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
 	"path"
 	"sherry/shr/constants"
 	"sherry/shr/helpers"
+	"time"
 )
 
 type Source struct {
@@ -29,6 +29,8 @@ type Watcher struct {
 	HashesId  string `json:"hashesId"`
 	UserId    string `json:"userId"`
 	Complete  bool   `json:"complete"`
+	// Ignore holds gitignore style patterns applied on top of the .sherryignore file of the folder
+	Ignore []string `json:"ignore,omitempty"`
 }
 
 type Config struct {
@@ -46,7 +48,17 @@ type Credentials struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
 	ExpiresIn    uint64 `json:"expiresIn"`
-	Expired      bool   `json:"expired"`
+	// IssuedAt is the unix time the access token was received, ExpiresIn seconds are counted from it
+	IssuedAt int64 `json:"issuedAt,omitempty"`
+	Expired  bool  `json:"expired"`
+}
+
+// ExpiresAt returns the expiry time of the access token, ok is false if it is unknown
+func (c Credentials) ExpiresAt() (time.Time, bool) {
+	if c.IssuedAt == 0 || c.ExpiresIn == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(c.IssuedAt, 0).Add(time.Duration(c.ExpiresIn) * time.Second), true
 }
 
 type AuthorizationConfig struct {
@@ -80,52 +92,48 @@ func SetAuthConfig(c *AuthorizationConfig) {
 	globalAuthConfig = c
 }
 
-func ReadConfig() *Config {
+func ReadConfig() (*Config, error) {
 	file, err := os.ReadFile(path.Join(configPath, constants.ConfigFile))
 
 	if err != nil {
-		helpers.PrintErr(fmt.Sprintf("Can't find configuration, searching \"%s\"", configPath))
-		return nil
+		return nil, helpers.FailureError("Can't find configuration, searching \"%s\"", configPath)
 	}
 
 	var c Config
 	if err := json.Unmarshal(file, &c); err != nil {
-		helpers.PrintErr(fmt.Sprintf("Unable to parse configuration file: %s", err))
-		return nil
+		return nil, helpers.FailureError("Unable to parse configuration file: %s", err)
 	}
 
-	return &c
+	return &c, nil
 }
 
-func ReadAuthConfig() *AuthorizationConfig {
+func ReadAuthConfig() (*AuthorizationConfig, error) {
 	file, err := os.ReadFile(path.Join(configPath, constants.AuthConfigFile))
 
 	if err != nil {
-		helpers.PrintErr(fmt.Sprintf("Can't find authorization configuration, searching \"%s\"", configPath))
-		return nil
+		return nil, helpers.FailureError("Can't find authorization configuration, searching \"%s\"", configPath)
 	}
 
 	var c AuthorizationConfig
 	if err := json.Unmarshal(file, &c); err != nil {
-		helpers.PrintErr(fmt.Sprintf("Unable to parse authorization configuration file: %s", err))
-		return nil
+		return nil, helpers.FailureError("Unable to parse authorization configuration file: %s", err)
 	}
 
-	return &c
+	return &c, nil
 }
 
 func SetupConfig(overwritePath string) error {
 	configPath = ResolveConfigPath(overwritePath)
 
-	c := ReadConfig()
-	if c == nil {
-		return errors.New("can't find configuration")
+	c, err := ReadConfig()
+	if err != nil {
+		return err
 	}
 	SetConfig(c)
 
-	auth := ReadAuthConfig()
-	if auth == nil {
-		return errors.New("can't find authorization configuration")
+	auth, err := ReadAuthConfig()
+	if err != nil {
+		return err
 	}
 	SetAuthConfig(auth)
 
@@ -144,27 +152,49 @@ func GetAuthConfig() *AuthorizationConfig {
 	return globalAuthConfig
 }
 
-func CommitConfig() {
+// FindWatcher returns the watcher whose local path contains the given prepared path
+func FindWatcher(p string) (*Watcher, error) {
+	for _, w := range globalConfig.Watchers {
+		isChild, err := helpers.IsChildPath(helpers.PreparePath(w.LocalPath), p)
+		if err != nil {
+			return nil, err
+		}
+		if isChild {
+			return &w, nil
+		}
+	}
+	return nil, nil
+}
+
+func CommitConfig() error {
 	data, _ := json.MarshalIndent(globalConfig, "", "  ")
 	err := os.WriteFile(path.Join(configPath, constants.ConfigFile), data, 0644)
 	if err != nil {
-		helpers.PrintErr(fmt.Sprintf("Unable to save configuration: %s", err))
-		return
+		return helpers.FailureError("Unable to save configuration: %s", err)
 	}
+	return nil
 }
 
-func CommitAuth() {
+func CommitAuth() error {
 	data, _ := json.MarshalIndent(globalAuthConfig, "", "  ")
 	err := os.WriteFile(path.Join(configPath, constants.AuthConfigFile), data, 0644)
 	if err != nil {
-		helpers.PrintErr(fmt.Sprintf("Unable to save authorization configuration: %s", err))
-		return
+		return helpers.FailureError("Unable to save authorization configuration: %s", err)
 	}
+	return nil
 }
 
-func WithCommit(fn func() bool) {
-	if fn() {
-		CommitConfig()
-		CommitAuth()
+// WithCommit runs the command and saves the configuration if the command reports changes,
+// changes are saved even if the command fails partway through.
+func WithCommit(fn func() (bool, error)) error {
+	commit, err := fn()
+	if commit {
+		if e := CommitConfig(); e != nil && err == nil {
+			err = e
+		}
+		if e := CommitAuth(); e != nil && err == nil {
+			err = e
+		}
 	}
+	return err
 }
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"path"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"time"
+)
+
+// ExpiringGrant is a permission granted until ExpiresAt, it is revoked by the sweep with credentials of GrantedBy
+type ExpiringGrant struct {
+	FolderId  string    `json:"folderId"`
+	Folder    string    `json:"folder"`
+	UserId    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	GrantedBy string    `json:"grantedBy"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ExpiryLedger keeps expiring grants locally because the server has no expiry of permissions
+type ExpiryLedger struct {
+	Grants []ExpiringGrant `json:"grants"`
+}
+
+func (ledger *ExpiryLedger) Find(folderId string, userId string) *ExpiringGrant {
+	i := helpers.IndexOfFunc(ledger.Grants, func(g ExpiringGrant) bool {
+		return g.FolderId == folderId && g.UserId == userId
+	})
+	if i == -1 {
+		return nil
+	}
+	return &ledger.Grants[i]
+}
+
+// Set replaces the grant of the same user and folder
+func (ledger *ExpiryLedger) Set(grant ExpiringGrant) {
+	ledger.Remove(grant.FolderId, grant.UserId)
+	ledger.Grants = append(ledger.Grants, grant)
+}
+
+// Remove drops the grant, the user then keeps the permission until it is revoked
+func (ledger *ExpiryLedger) Remove(folderId string, userId string) {
+	ledger.Grants = helpers.EmptyIfNull(helpers.Filter(ledger.Grants, func(g ExpiringGrant) bool {
+		return g.FolderId != folderId || g.UserId != userId
+	}))
+}
+
+func GetExpiryLedgerPath() string {
+	return path.Join(configPath, constants.ExpiryFile)
+}
+
+// ReadExpiryLedger returns the ledger, an empty one if nothing was granted with expiry yet
+func ReadExpiryLedger() (*ExpiryLedger, error) {
+	ledger := ExpiryLedger{}
+	if err := readJsonFile(GetExpiryLedgerPath(), &ledger); err != nil {
+		return nil, helpers.FailureError("Can't read expiring permissions %s: %s", GetExpiryLedgerPath(), err)
+	}
+	ledger.Grants = helpers.EmptyIfNull(ledger.Grants)
+	return &ledger, nil
+}
+
+func CommitExpiryLedger(ledger *ExpiryLedger) error {
+	if err := writeJsonFileAtomic(GetExpiryLedgerPath(), ledger); err != nil {
+		return helpers.FailureError("Unable to save expiring permissions: %s", err)
+	}
+	return nil
+}
+
+// UpdateExpiryLedger reads the ledger, applies fn and saves it
+func UpdateExpiryLedger(fn func(ledger *ExpiryLedger)) error {
+	ledger, err := ReadExpiryLedger()
+	if err != nil {
+		return err
+	}
+	fn(ledger)
+	return CommitExpiryLedger(ledger)
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"path"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+)
+
+// Groups maps local group names to usernames or ids of their members, they are kept out of config.json
+// which is also rewritten by the demon
+type Groups struct {
+	Groups map[string][]string `json:"groups"`
+}
+
+func GetGroupsPath() string {
+	return path.Join(configPath, constants.GroupsFile)
+}
+
+// ReadGroups returns the local groups, none if no group was created yet
+func ReadGroups() (*Groups, error) {
+	groups := Groups{}
+	if err := readJsonFile(GetGroupsPath(), &groups); err != nil {
+		return nil, helpers.FailureError("Can't read groups %s: %s", GetGroupsPath(), err)
+	}
+	if groups.Groups == nil {
+		groups.Groups = map[string][]string{}
+	}
+	return &groups, nil
+}
+
+func CommitGroups(groups *Groups) error {
+	if err := writeJsonFileAtomic(GetGroupsPath(), groups); err != nil {
+		return helpers.FailureError("Unable to save groups: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"time"
+)
+
+// IndexEntry is the state of a file at the moment it was last synchronized
+type IndexEntry struct {
+	Path         string    `json:"path"`
+	Hash         string    `json:"hash"`
+	Size         uint64    `json:"size"`
+	ModTime      time.Time `json:"modTime"`
+	SherryFileID string    `json:"sherryFileId"`
+	UpdatedAt    uint64    `json:"updatedAt"`
+}
+
+// Index is the local index of a watcher, stored in the hashes dir under Watcher.HashesId
+type Index struct {
+	Files map[string]IndexEntry `json:"files"`
+}
+
+func NewIndex() *Index {
+	return &Index{Files: map[string]IndexEntry{}}
+}
+
+// Set records the file at localPath as synchronized with the server file of the given hash
+func (index *Index) Set(rel string, localPath string, hash string, sherryFileID string, updatedAt uint64) error {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	index.Files[rel] = IndexEntry{
+		Path:         rel,
+		Hash:         hash,
+		Size:         uint64(stat.Size()),
+		ModTime:      stat.ModTime(),
+		SherryFileID: sherryFileID,
+		UpdatedAt:    updatedAt,
+	}
+	return nil
+}
+
+// UnchangedHash returns the recorded hash if the file still has the size and modification time of the last
+// synchronization, the file is then not hashed again. A nil index knows no file.
+func (index *Index) UnchangedHash(rel string, size uint64, modTime time.Time) (string, bool) {
+	if index == nil {
+		return "", false
+	}
+	entry, ok := index.Files[rel]
+	if !ok || entry.Size != size || !entry.ModTime.Equal(modTime) {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+func GetIndexPath(hashesId string) string {
+	return path.Join(configPath, constants.HashesDir, hashesId+".json")
+}
+
+// ReadIndex returns the index of the watcher, an empty one if nothing was synchronized yet
+func ReadIndex(hashesId string) (*Index, error) {
+	index := NewIndex()
+	if err := readJsonFile(GetIndexPath(hashesId), index); err != nil {
+		return nil, helpers.FailureError("Can't read local index %s: %s", GetIndexPath(hashesId), err)
+	}
+	if index.Files == nil {
+		index.Files = map[string]IndexEntry{}
+	}
+	return index, nil
+}
+
+func CommitIndex(hashesId string, index *Index) error {
+	if err := writeJsonFileAtomic(GetIndexPath(hashesId), index); err != nil {
+		return helpers.FailureError("Unable to save local index: %s", err)
+	}
+	return nil
+}
+
+func RemoveIndex(hashesId string) error {
+	err := os.Remove(GetIndexPath(hashesId))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return helpers.FailureError("Unable to remove local index: %s", err)
+	}
+	return nil
+}
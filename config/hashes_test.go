@@ -0,0 +1,74 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	configPath = t.TempDir()
+
+	index, err := ReadIndex("watcher-1")
+	assert.NoError(t, err)
+	assert.Empty(t, index.Files)
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(local, []byte("content"), 0644))
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(local, modTime, modTime))
+	assert.NoError(t, index.Set("docs/a.txt", local, "hash-1", "file-1", 1000))
+	assert.NoError(t, CommitIndex("watcher-1", index))
+
+	read, err := ReadIndex("watcher-1")
+	assert.NoError(t, err)
+	assert.Len(t, read.Files, 1)
+	entry := read.Files["docs/a.txt"]
+	assert.Equal(t, "hash-1", entry.Hash)
+	assert.Equal(t, uint64(7), entry.Size)
+	assert.True(t, entry.ModTime.Equal(modTime))
+	assert.Equal(t, "file-1", entry.SherryFileID)
+	assert.Equal(t, uint64(1000), entry.UpdatedAt)
+	assert.NoFileExists(t, GetIndexPath("watcher-1")+".tmp")
+
+	assert.NoError(t, RemoveIndex("watcher-1"))
+	assert.NoError(t, RemoveIndex("watcher-1"))
+	read, err = ReadIndex("watcher-1")
+	assert.NoError(t, err)
+	assert.Empty(t, read.Files)
+}
+
+func TestUnchangedHash(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	index := NewIndex()
+	index.Files["a.txt"] = IndexEntry{Path: "a.txt", Hash: "hash-1", Size: 7, ModTime: modTime}
+
+	tests := []struct {
+		name    string
+		rel     string
+		size    uint64
+		modTime time.Time
+		want    bool
+	}{
+		{name: "Test unchanged file", rel: "a.txt", size: 7, modTime: modTime, want: true},
+		{name: "Test same instant in another zone", rel: "a.txt", size: 7, modTime: modTime.In(time.FixedZone("CET", 3600)), want: true},
+		{name: "Test changed size", rel: "a.txt", size: 8, modTime: modTime},
+		{name: "Test changed modification time", rel: "a.txt", size: 7, modTime: modTime.Add(time.Second)},
+		{name: "Test unknown file", rel: "b.txt", size: 7, modTime: modTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, ok := index.UnchangedHash(tt.rel, tt.size, tt.modTime)
+			assert.Equal(t, tt.want, ok)
+			if tt.want {
+				assert.Equal(t, "hash-1", hash)
+			}
+		})
+	}
+
+	var missing *Index
+	_, ok := missing.UnchangedHash("a.txt", 7, modTime)
+	assert.False(t, ok)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+)
+
+// readJsonFile decodes the file into v, v is left untouched if the file does not exist
+func readJsonFile(p string, v interface{}) error {
+	file, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(file, v)
+}
+
+// writeJsonFileAtomic writes v through a temporary file, so an interrupted write keeps the previous file
+func writeJsonFileAtomic(p string, v interface{}) error {
+	if err := os.MkdirAll(path.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(v, "", "  ")
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
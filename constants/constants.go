@@ -3,10 +3,14 @@ package constants
 const EnvConfigDir = "SHERRY_CONFIG_PATH"
 const AnvApiUrl = "SHERRY_API_URL"
 const EnvSocketUrl = "SHERRY_SOCKET_URL"
+const EnvUser = "SHERRY_USER"
 
 const ConfigDir = ".sherry"
 const ConfigFile = "config.json"
 const AuthConfigFile = "auth.json"
+const HashesDir = "hashes"
+const ExpiryFile = "expiry.json"
+const GroupsFile = "groups.json"
 
 const MaxFileSize = 1e9
 const MaxDirSize = 2e9
@@ -14,3 +18,22 @@ const MaxDirSize = 2e9
 const DefaultMaxFileSize = 1e6
 const DefaultMaxDirSize = 5e6
 const DefaultAllowDir = true
+
+// Process exit codes, see README for the description
+const (
+	ExitOk            = 0
+	ExitFailure       = 1
+	ExitUsage         = 2
+	ExitAuthExpired   = 3
+	ExitNotFound      = 4
+	ExitNetwork       = 5
+	ExitConflict      = 6
+	ExitInputRequired = 7
+)
+
+// Exit codes of shr service status, they don't overlap the codes above so monitoring can tell them from failures
+const (
+	ExitServiceStopped = 10
+	ExitServiceStale   = 11
+	ExitServiceUnknown = 12
+)
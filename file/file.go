@@ -0,0 +1,248 @@
+package file
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+	"path/filepath"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"sherry/shr/policy"
+)
+
+type Target = struct {
+	Watcher     config.Watcher
+	Source      config.Source
+	Credentials config.Credentials
+	LocalPath   string
+	Path        string
+}
+
+type Result = struct {
+	Action    string            `json:"action"`
+	Folder    string            `json:"folder"`
+	LocalPath string            `json:"localPath"`
+	Path      string            `json:"path"`
+	File      *api.FileResponse `json:"file,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// resolveTarget finds the watched folder containing localPath and the path of the file on the server
+func resolveTarget(localPath string) (*Target, error) {
+	localPath = helpers.PreparePath(localPath)
+
+	watcher, err := config.FindWatcher(localPath)
+	if err != nil {
+		return nil, helpers.FailureError("Error while checking path")
+	}
+	if watcher == nil {
+		return nil, helpers.NotFoundError("%s is not inside a watched folder", localPath)
+	}
+	source, ok := config.GetConfig().Sources[watcher.Source]
+	if !ok {
+		return nil, helpers.NotFoundError("Source of the watcher %s not found", watcher.LocalPath)
+	}
+	if source.Access == api.PermissionRoleRead {
+		return nil, helpers.UsageError("You have read access to %s and can't change files", source.Name)
+	}
+
+	root := helpers.PreparePath(watcher.LocalPath)
+	rel, err := filepath.Rel(root, localPath)
+	if err != nil || rel == "." {
+		return nil, helpers.UsageError("%s is not a file inside %s", localPath, root)
+	}
+
+	credentials, err := auth.GetActiveUserById(watcher.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Target{
+		Watcher:     *watcher,
+		Source:      source,
+		Credentials: *credentials,
+		LocalPath:   localPath,
+		Path:        filepath.ToSlash(rel),
+	}, nil
+}
+
+// updateIndex applies fn to the local index of the watcher, so the next sync knows the file is up to date
+func updateIndex(target *Target, fn func(index *config.Index) error) error {
+	index, err := config.ReadIndex(target.Watcher.HashesId)
+	if err != nil {
+		return err
+	}
+	if err := fn(index); err != nil {
+		return helpers.FailureError("Can't update local index: %s", err)
+	}
+	return config.CommitIndex(target.Watcher.HashesId, index)
+}
+
+func printResults(results []Result, firstErr error) error {
+	helpers.PrintResult(results, func() {
+		for _, r := range results {
+			if r.Error != "" {
+				helpers.PrintErr(fmt.Sprintf("%s %s: %s", r.Action, r.LocalPath, r.Error))
+				continue
+			}
+			switch r.Action {
+			case "put":
+				helpers.PrintMessage(fmt.Sprintf("Uploaded %s to %s (%s)", r.LocalPath, r.Folder, humanize.Bytes(r.File.Size)))
+			case "rm":
+				helpers.PrintMessage(fmt.Sprintf("Deleted %s from %s", r.Path, r.Folder))
+			case "mv":
+				helpers.PrintMessage(fmt.Sprintf("Moved %s to %s in %s", r.File.OldPath, r.Path, r.Folder))
+			}
+		}
+	})
+	return firstErr
+}
+
+func putFile(localPath string) (*Result, error) {
+	target, err := resolveTarget(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(target.LocalPath)
+	if err != nil {
+		return nil, helpers.NotFoundError("Can't read %s: %s", target.LocalPath, err)
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, helpers.UsageError("%s is not a file", target.LocalPath)
+	}
+	violations, err := policy.FromSource(target.Source).CheckLocalFile(target.LocalPath, target.Path)
+	if err != nil {
+		return nil, helpers.FailureError("Can't read %s: %s", target.LocalPath, err)
+	}
+	if len(violations) != 0 {
+		return nil, helpers.UsageError("%s breaks the rules of %s: %s", target.LocalPath, target.Source.Name, policy.FormatViolations(violations))
+	}
+
+	hash, err := helpers.HashFile(target.LocalPath)
+	if err != nil {
+		return nil, helpers.FailureError(err.Error())
+	}
+
+	response, err := api.FolderFileUpload(target.Source.Id, target.Path, target.LocalPath, hash, uint64(stat.Size()), target.Credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	err = updateIndex(target, func(index *config.Index) error {
+		return index.Set(target.Path, target.LocalPath, hash, response.SherryFileID, response.UpdatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Action: "put", Folder: target.Source.Name, LocalPath: target.LocalPath, Path: target.Path, File: response}, nil
+}
+
+func removeFile(localPath string, keepLocal bool) (*Result, error) {
+	target, err := resolveTarget(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := api.FolderFileDelete(target.Source.Id, target.Path, target.Credentials.AccessToken); err != nil {
+		return nil, err
+	}
+	if !keepLocal {
+		if err := os.Remove(target.LocalPath); err != nil && !os.IsNotExist(err) {
+			return nil, helpers.FailureError("Deleted on the server, but can't delete local file: %s", err)
+		}
+	}
+	err = updateIndex(target, func(index *config.Index) error {
+		delete(index.Files, target.Path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Action: "rm", Folder: target.Source.Name, LocalPath: target.LocalPath, Path: target.Path}, nil
+}
+
+// forEachFile runs fn for every path, continuing after failures and returning the first error
+func forEachFile(action string, paths []string, fn func(string) (*Result, error)) error {
+	var results []Result
+	var firstErr error
+	for _, p := range paths {
+		result, err := fn(p)
+		if err != nil && len(paths) == 1 {
+			return err
+		}
+		if err != nil {
+			result = &Result{Action: action, LocalPath: helpers.PreparePath(p), Error: err.Error()}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		results = append(results, *result)
+	}
+	if firstErr != nil {
+		firstErr = helpers.WrapError(helpers.GetExitCode(firstErr), firstErr, fmt.Sprintf("Failed to %s some of the files", action))
+	}
+	return printResults(results, firstErr)
+}
+
+func PutFiles(paths []string) (bool, error) {
+	return false, forEachFile("put", paths, putFile)
+}
+
+func RemoveFiles(paths []string, keepLocal bool) (bool, error) {
+	return false, forEachFile("rm", paths, func(p string) (*Result, error) {
+		return removeFile(p, keepLocal)
+	})
+}
+
+func MoveFile(src string, dst string) (bool, error) {
+	source, err := resolveTarget(src)
+	if err != nil {
+		return false, err
+	}
+	destination, err := resolveTarget(dst)
+	if err != nil {
+		return false, err
+	}
+	if source.Watcher.LocalPath != destination.Watcher.LocalPath {
+		return false, helpers.UsageError("Files can be moved only inside the same watched folder")
+	}
+	if helpers.IsExists(destination.LocalPath) {
+		return false, helpers.ConflictError("%s already exists", destination.LocalPath)
+	}
+
+	response, err := api.FolderFileMove(source.Source.Id, source.Path, destination.Path, source.Credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	if helpers.IsExists(source.LocalPath) {
+		if err := os.MkdirAll(filepath.Dir(destination.LocalPath), os.ModePerm); err != nil {
+			return false, helpers.FailureError("Moved on the server, but can't move local file: %s", err)
+		}
+		if err := os.Rename(source.LocalPath, destination.LocalPath); err != nil {
+			return false, helpers.FailureError("Moved on the server, but can't move local file: %s", err)
+		}
+	}
+	err = updateIndex(source, func(index *config.Index) error {
+		delete(index.Files, source.Path)
+		if !helpers.IsExists(destination.LocalPath) {
+			return nil
+		}
+		return index.Set(destination.Path, destination.LocalPath, response.Hash, response.SherryFileID, response.UpdatedAt)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return false, printResults([]Result{{
+		Action:    "mv",
+		Folder:    source.Source.Name,
+		LocalPath: destination.LocalPath,
+		Path:      destination.Path,
+		File:      response,
+	}}, nil)
+}
@@ -0,0 +1,59 @@
+package file
+
+import (
+	flag "github.com/jessevdk/go-flags"
+	"sherry/shr/config"
+)
+
+type Options struct {
+	Put    PutOptions    `command:"put" description:"Upload files of a watched folder"`
+	Remove RemoveOptions `command:"rm" description:"Delete files of a watched folder"`
+	Move   MoveOptions   `command:"mv" description:"Move or rename a file of a watched folder"`
+}
+
+type PutOptions struct {
+	Args struct {
+		Paths []flag.Filename `positional-arg-name:"path" description:"Files inside watched folder"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type RemoveOptions struct {
+	KeepLocal bool `long:"keep-local" short:"k" description:"Delete file only on the server"`
+	Args      struct {
+		Paths []flag.Filename `positional-arg-name:"path" description:"Files inside watched folder"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type MoveOptions struct {
+	Args struct {
+		Source      flag.Filename `positional-arg-name:"source" description:"File inside watched folder"`
+		Destination flag.Filename `positional-arg-name:"destination" description:"New path inside the same watched folder"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func toStrings(paths []flag.Filename) []string {
+	result := make([]string, len(paths))
+	for i, p := range paths {
+		result[i] = string(p)
+	}
+	return result
+}
+
+func ApplyCommand(cmd *flag.Command, options Options) error {
+	if cmd.Active.Name != "file" {
+		return nil
+	}
+
+	return config.WithCommit(func() (bool, error) {
+		switch cmd.Active.Active.Name {
+		case "put":
+			return PutFiles(toStrings(options.Put.Args.Paths))
+		case "rm":
+			return RemoveFiles(toStrings(options.Remove.Args.Paths), options.Remove.KeepLocal)
+		case "mv":
+			return MoveFile(string(options.Move.Args.Source), string(options.Move.Args.Destination))
+		default:
+			return false, nil
+		}
+	})
+}
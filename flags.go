@@ -1,21 +1,36 @@
 package main
 
 import (
+	"errors"
 	flag "github.com/jessevdk/go-flags"
 	"sherry/shr/auth"
+	"sherry/shr/file"
 	"sherry/shr/folder"
+	"sherry/shr/group"
+	"sherry/shr/helpers"
 	"sherry/shr/service"
 )
 
 type Options struct {
-	ConfigPath flag.Filename   `long:"config" short:"c" description:"Path to configuration folder"`
-	Auth       auth.Options    `command:"auth" description:"Authenticate"`
-	Folder     folder.Options  `command:"folder" description:"Folder operations"`
-	Service    service.Options `command:"service" description:"service operations"`
+	ConfigPath     flag.Filename   `long:"config" short:"c" description:"Path to configuration folder"`
+	NonInteractive bool            `long:"non-interactive" description:"Fail instead of prompting for missing values, enabled automatically when stdin is not a terminal"`
+	Output         string          `long:"output" short:"o" choice:"table" choice:"json" choice:"yaml" default:"table" description:"Output format"`
+	Auth           auth.Options    `command:"auth" description:"Authenticate"`
+	Folder         folder.Options  `command:"folder" description:"Folder operations"`
+	File           file.Options    `command:"file" description:"File operations in watched folders"`
+	Group          group.Options   `command:"group" description:"Manage local groups of users"`
+	Service        service.Options `command:"service" description:"service operations"`
 }
 
-func applyCommand(cmd *flag.Command, options Options) {
-	auth.ApplyCommand(cmd, options.Auth)
-	folder.ApplyCommands(cmd, options.Folder)
-	service.ApplyCommand(cmd, options.Service)
+func applyCommand(cmd *flag.Command, options Options) error {
+	helpers.SetOutputFormat(options.Output)
+	helpers.SetInteractive(!options.NonInteractive && helpers.IsStdinTerminal())
+
+	return errors.Join(
+		auth.ApplyCommand(cmd, options.Auth),
+		folder.ApplyCommands(cmd, options.Folder),
+		file.ApplyCommand(cmd, options.File),
+		group.ApplyCommand(cmd, options.Group),
+		service.ApplyCommand(cmd, options.Service),
+	)
 }
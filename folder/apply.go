@@ -0,0 +1,227 @@
+package folder
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"sort"
+	"strings"
+)
+
+// PermissionFile declares the users and roles per folder, e.g.
+//
+//	folders:
+//	  docs:
+//	    bob: write
+//	    carol: read
+type PermissionFile = struct {
+	Folders map[string]map[string]string `yaml:"folders"`
+}
+
+type PermissionChange = struct {
+	Folder   string `json:"folder"`
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+	Action   string `json:"action"`
+	Role     string `json:"role,omitempty"`
+	OldRole  string `json:"oldRole,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type PermissionApplyResult = struct {
+	DryRun  bool               `json:"dryRun"`
+	Changes []PermissionChange `json:"changes"`
+	Failed  int                `json:"failed"`
+}
+
+type folderPermissions = struct {
+	Folder  *api.ResponseFolder
+	Current []PermissionEntry
+	Desired []PermissionEntry
+}
+
+func readPermissionFile(path string) (*PermissionFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, helpers.UsageError("Can't read %s: %s", path, err)
+	}
+	var file PermissionFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, helpers.UsageError("Can't parse %s: %s", path, err)
+	}
+	if len(file.Folders) == 0 {
+		return nil, helpers.UsageError("No folders declared in %s", path)
+	}
+	return &file, nil
+}
+
+func parseRole(role string) (string, error) {
+	upper := strings.ToUpper(role)
+	if upper != api.PermissionRoleRead && upper != api.PermissionRoleWrite {
+		return "", helpers.UsageError("Invalid role %q, use read or write", role)
+	}
+	return upper, nil
+}
+
+// planPermissions grants the desired roles which differ from the current ones,
+// with prune the access of users which are not desired is revoked, owners are never changed
+func planPermissions(folder string, current []PermissionEntry, desired []PermissionEntry, prune bool) []PermissionChange {
+	var changes []PermissionChange
+	for _, d := range desired {
+		c := helpers.Find(current, func(p PermissionEntry) bool {
+			return p.UserId == d.UserId
+		})
+		if c != nil && c.Role == d.Role {
+			continue
+		}
+		change := PermissionChange{Folder: folder, UserId: d.UserId, Username: d.Username, Action: api.PermissionActionGrant, Role: d.Role}
+		if c != nil {
+			change.OldRole = c.Role
+		}
+		changes = append(changes, change)
+	}
+	if prune {
+		for _, c := range current {
+			isDesired := helpers.Find(desired, func(p PermissionEntry) bool {
+				return p.UserId == c.UserId
+			}) != nil
+			if isDesired || c.Role == api.PermissionRoleOwner {
+				continue
+			}
+			changes = append(changes, PermissionChange{Folder: folder, UserId: c.UserId, Username: c.Username, Action: api.PermissionActionRefuse, OldRole: c.Role})
+		}
+	}
+	return changes
+}
+
+// loadFolderPermissions resolves all folders and users of the file before anything is changed
+func loadFolderPermissions(file *PermissionFile, credentials config.Credentials) ([]folderPermissions, error) {
+	names := make([]string, 0, len(file.Folders))
+	for name := range file.Folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []folderPermissions
+	for _, name := range names {
+		folder, err := getOwnedFolder(name, credentials)
+		if err != nil {
+			return nil, err
+		}
+		current, err := getPermissions(folder, credentials.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		var desired []PermissionEntry
+		for target, role := range file.Folders[name] {
+			if role, err = parseRole(role); err != nil {
+				return nil, err
+			}
+			user, err := getTargetUser(target, credentials.AccessToken)
+			if err != nil {
+				return nil, err
+			}
+			if user.UserId == folder.UserId {
+				return nil, helpers.UsageError("Can't change the role of the owner of %s", folder.Name)
+			}
+			desired = append(desired, PermissionEntry{UserId: user.UserId, Username: user.Username, Email: user.Email, Role: role})
+		}
+		sortPermissions(desired)
+		result = append(result, folderPermissions{Folder: folder, Current: current, Desired: desired})
+	}
+	return result, nil
+}
+
+func printPermissionChanges(result PermissionApplyResult) {
+	if len(result.Changes) == 0 {
+		helpers.PrintMessage("Permissions are up to date")
+		return
+	}
+	for _, c := range result.Changes {
+		line := fmt.Sprintf("  %-6s %s %s", strings.ToLower(c.Action), c.Folder, helpers.If(c.Username != "", func() string {
+			return c.Username
+		}, func() string {
+			return c.UserId
+		}))
+		switch {
+		case c.Action == api.PermissionActionRefuse:
+			line += fmt.Sprintf(" (was %s)", c.OldRole)
+		case c.OldRole != "":
+			line += fmt.Sprintf(" %s (was %s)", c.Role, c.OldRole)
+		default:
+			line += " " + c.Role
+		}
+		if c.Error != "" {
+			line = helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, fmt.Sprintf("%s: %s", line, c.Error))
+		}
+		helpers.PrintMessage(line)
+	}
+	if result.DryRun {
+		helpers.PrintMessage(fmt.Sprintf("%d changes planned, nothing was changed", len(result.Changes)))
+	} else {
+		helpers.PrintMessage(fmt.Sprintf("%d changes done, %d failed", len(result.Changes)-result.Failed, result.Failed))
+	}
+}
+
+func ApplyPermissions(user string, path string, dryRun bool, prune bool) (bool, error) {
+	file, err := readPermissionFile(path)
+	if err != nil {
+		return false, err
+	}
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+	folders, err := loadFolderPermissions(file, *credentials)
+	if err != nil {
+		return false, err
+	}
+
+	var changes []PermissionChange
+	for _, f := range folders {
+		changes = append(changes, planPermissions(f.Folder.Name, f.Current, f.Desired, prune)...)
+	}
+	result := PermissionApplyResult{DryRun: dryRun, Changes: helpers.EmptyIfNull(changes)}
+
+	var firstErr error
+	if !dryRun {
+		for i, c := range result.Changes {
+			folder := helpers.Find(folders, func(f folderPermissions) bool {
+				return f.Folder.Name == c.Folder
+			})
+			var err error
+			if c.Action == api.PermissionActionRefuse {
+				err = api.FolderPermissionRevoke(folder.Folder.SherryId, c.UserId, credentials.AccessToken)
+			} else {
+				err = api.FolderPermission(folder.Folder.SherryId, c.UserId, api.PayloadFolderPermission{
+					Action: c.Action,
+					Role:   c.Role,
+				}, credentials.AccessToken)
+			}
+			if err == nil {
+				err = forgetExpiry(folder.Folder.SherryId, c.UserId)
+			}
+			if err != nil {
+				result.Changes[i].Error = err.Error()
+				result.Failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+
+	helpers.PrintResult(result, func() {
+		printPermissionChanges(result)
+	})
+
+	if firstErr != nil {
+		return false, helpers.WrapError(helpers.GetExitCode(firstErr), firstErr, fmt.Sprintf("%d of %d changes failed", result.Failed, len(result.Changes)))
+	}
+	return false, nil
+}
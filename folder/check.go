@@ -0,0 +1,95 @@
+package folder
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"path/filepath"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sherry/shr/policy"
+	"sort"
+)
+
+type CheckResult = struct {
+	Folder     string             `json:"folder"`
+	LocalPath  string             `json:"localPath"`
+	Files      int                `json:"files"`
+	Size       uint64             `json:"size"`
+	Valid      bool               `json:"valid"`
+	Violations []policy.Violation `json:"violations"`
+}
+
+func toPolicyFiles(root string, files map[string]localFile, detectTypes bool) ([]policy.File, error) {
+	var result []policy.File
+	for p, f := range files {
+		entry := policy.File{Path: p, Size: f.Size, IsDir: f.IsDir}
+		if detectTypes && !f.IsDir {
+			mimeType, err := policy.DetectMimeType(filepath.Join(root, filepath.FromSlash(p)))
+			if err != nil {
+				return nil, err
+			}
+			entry.MimeType = mimeType
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+func printCheckResult(result CheckResult) {
+	helpers.PrintMessage(fmt.Sprintf("Folder %s at %s: %d files, %s", result.Folder, result.LocalPath, result.Files, humanize.Bytes(result.Size)))
+	if result.Valid {
+		helpers.PrintMessage("All files follow the folder rules")
+		return
+	}
+	for _, v := range result.Violations {
+		helpers.PrintMessage(helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, fmt.Sprintf("  %-10s %s: %s", v.Kind, v.Path, v.Message)))
+	}
+}
+
+func CheckSharedFolder(localPath string) (bool, error) {
+	watcher, source, err := findWatchedFolder(localPath)
+	if err != nil {
+		return false, err
+	}
+
+	root := helpers.PreparePath(watcher.LocalPath)
+	ignored, err := loadIgnore(root, *watcher)
+	if err != nil {
+		return false, err
+	}
+	local, err := listLocalTree(root, ignored)
+	if err != nil {
+		return false, helpers.FailureError("Can't read %s: %s", root, err)
+	}
+
+	rules := policy.FromSource(*source)
+	files, err := toPolicyFiles(root, local, len(rules.AllowedFileTypes) != 0)
+	if err != nil {
+		return false, helpers.FailureError("Can't read %s: %s", root, err)
+	}
+
+	result := CheckResult{
+		Folder:     source.Name,
+		LocalPath:  root,
+		Violations: helpers.EmptyIfNull(rules.Check(files)),
+	}
+	for _, f := range files {
+		if !f.IsDir {
+			result.Files++
+			result.Size += f.Size
+		}
+	}
+	result.Valid = len(result.Violations) == 0
+
+	helpers.PrintResult(result, func() {
+		printCheckResult(result)
+	})
+
+	if !result.Valid {
+		return false, helpers.NewError(constants.ExitFailure, "%d violations of the folder rules found", len(result.Violations))
+	}
+	return false, nil
+}
@@ -0,0 +1,116 @@
+package folder
+
+import (
+	"fmt"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+)
+
+type DeleteResult = struct {
+	Folder   string   `json:"folder"`
+	Id       string   `json:"id"`
+	Sources  []string `json:"sources"`
+	Watchers []string `json:"watchers"`
+}
+
+// getOwnedFolder resolves a folder of the user by id or name
+func getOwnedFolder(folder string, credentials config.Credentials) (*api.ResponseFolder, error) {
+	response, err := findFolder(folder, credentials)
+	if err != nil {
+		return nil, err
+	}
+	if response.UserId != credentials.UserId {
+		return nil, helpers.UsageError("Only the owner can manage folder %s", response.Name)
+	}
+	return response, nil
+}
+
+// removeFolderFromConfig drops the sources of every local user and their watchers for the deleted folder,
+// files of the watched directories are kept
+func removeFolderFromConfig(sherryId string) (*DeleteResult, error) {
+	return removeSourcesFromConfig(sherryId, func(s config.Source) bool {
+		return true
+	})
+}
+
+// removeSourcesFromConfig drops the matching sources of the folder and their watchers
+func removeSourcesFromConfig(sherryId string, match func(config.Source) bool) (*DeleteResult, error) {
+	conf := config.GetConfig()
+	result := DeleteResult{Id: sherryId, Sources: []string{}, Watchers: []string{}}
+
+	for key, s := range conf.Sources {
+		if s.Id == sherryId && match(s) {
+			result.Sources = append(result.Sources, key)
+			delete(conf.Sources, key)
+		}
+	}
+
+	var watchers []config.Watcher
+	for _, w := range conf.Watchers {
+		if !helpers.Includes(result.Sources, w.Source) {
+			watchers = append(watchers, w)
+			continue
+		}
+		result.Watchers = append(result.Watchers, w.LocalPath)
+		if err := config.RemoveIndex(w.HashesId); err != nil {
+			return nil, err
+		}
+	}
+	conf.Watchers = helpers.EmptyIfNull(watchers)
+
+	return &result, nil
+}
+
+func DeleteSharedFolder(user string, folder string, confirm string) (bool, error) {
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := getOwnedFolder(folder, *credentials)
+	if err != nil {
+		return false, err
+	}
+
+	helpers.PrintMessage(fmt.Sprintf("Folder %s and all its files will be deleted for every collaborator, type its name to confirm", response.Name))
+	_, err = helpers.Input(
+		"Folder name",
+		"--confirm <folder name>",
+		confirm,
+		helpers.GetEqualValidator(response.Name),
+		response.Name,
+		false,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if err := api.FolderDelete(response.SherryId, credentials.AccessToken); err != nil {
+		return false, err
+	}
+
+	result, err := removeFolderFromConfig(response.SherryId)
+	if err != nil {
+		return true, err
+	}
+	err = config.UpdateExpiryLedger(func(ledger *config.ExpiryLedger) {
+		ledger.Grants = helpers.EmptyIfNull(helpers.Filter(ledger.Grants, func(g config.ExpiringGrant) bool {
+			return g.FolderId != response.SherryId
+		}))
+	})
+	if err != nil {
+		return true, err
+	}
+	result.Folder = response.Name
+
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("Folder %s deleted", result.Folder))
+		for _, w := range result.Watchers {
+			helpers.PrintMessage(fmt.Sprintf("Stopped watching %s, local files are kept", w))
+		}
+	})
+
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package folder
+
+import (
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"sort"
+)
+
+type ChangeKind = string
+
+const (
+	ChangeNone     ChangeKind = ""
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "modified"
+	ChangeDeleted  ChangeKind = "deleted"
+)
+
+// fileChange describes how a file differs between the local tree and the server
+// relative to the state recorded in the index at the last synchronization
+type fileChange struct {
+	Path         string
+	Local        *localFile
+	Remote       *api.FileResponse
+	LocalChange  ChangeKind
+	RemoteChange ChangeKind
+}
+
+func (c fileChange) IsConflict() bool {
+	return c.LocalChange != ChangeNone && c.RemoteChange != ChangeNone
+}
+
+func getChange(exists bool, hash string, base config.IndexEntry, synced bool) ChangeKind {
+	switch {
+	case !exists && synced:
+		return ChangeDeleted
+	case !exists:
+		return ChangeNone
+	case !synced:
+		return ChangeAdded
+	case hash != base.Hash:
+		return ChangeModified
+	}
+	return ChangeNone
+}
+
+// diffTrees returns the files that differ between both sides sorted by path, directories are not compared.
+// Without an index entry a file present on both sides with different content is added on both sides.
+func diffTrees(local map[string]localFile, remote map[string]api.FileResponse, index *config.Index) []fileChange {
+	paths := map[string]bool{}
+	for p, l := range local {
+		if !l.IsDir {
+			paths[p] = true
+		}
+	}
+	for p, r := range remote {
+		if r.FileType != api.Dir {
+			paths[p] = true
+		}
+	}
+	if index != nil {
+		for p := range index.Files {
+			paths[p] = true
+		}
+	}
+
+	var changes []fileChange
+	for p := range paths {
+		change := fileChange{Path: p}
+		if l, ok := local[p]; ok && !l.IsDir {
+			change.Local = &l
+		}
+		if r, ok := remote[p]; ok && r.FileType != api.Dir {
+			change.Remote = &r
+		}
+		if change.Local == nil && change.Remote == nil {
+			// Deleted on both sides, the stale index entry is dropped on the next sync
+			continue
+		}
+		if change.Local != nil && change.Remote != nil && change.Local.Hash == change.Remote.Hash {
+			continue
+		}
+
+		base, synced := getIndexEntry(index, p)
+		if change.Local != nil {
+			change.LocalChange = getChange(true, change.Local.Hash, base, synced)
+		} else {
+			change.LocalChange = getChange(false, "", base, synced)
+		}
+		if change.Remote != nil {
+			change.RemoteChange = getChange(true, change.Remote.Hash, base, synced)
+		} else {
+			change.RemoteChange = getChange(false, "", base, synced)
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
@@ -0,0 +1,131 @@
+package folder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	type change = struct {
+		Path   string
+		Local  ChangeKind
+		Remote ChangeKind
+	}
+	synced := func(paths ...string) *config.Index {
+		index := config.NewIndex()
+		for _, p := range paths {
+			index.Files[p] = config.IndexEntry{Path: p, Hash: "1"}
+		}
+		return index
+	}
+
+	tests := []struct {
+		name   string
+		local  []localFile
+		remote []api.FileResponse
+		index  *config.Index
+		want   []change
+	}{
+		{
+			name:   "Test unchanged file",
+			local:  []localFile{{Path: "a", Hash: "1"}},
+			remote: []api.FileResponse{{Path: "a", Hash: "1"}},
+			index:  synced("a"),
+		},
+		{
+			name:  "Test added locally",
+			local: []localFile{{Path: "a", Hash: "1"}},
+			index: synced(),
+			want:  []change{{Path: "a", Local: ChangeAdded}},
+		},
+		{
+			name:   "Test added on server",
+			remote: []api.FileResponse{{Path: "a", Hash: "1"}},
+			index:  synced(),
+			want:   []change{{Path: "a", Remote: ChangeAdded}},
+		},
+		{
+			name:   "Test modified locally",
+			local:  []localFile{{Path: "a", Hash: "2"}},
+			remote: []api.FileResponse{{Path: "a", Hash: "1"}},
+			index:  synced("a"),
+			want:   []change{{Path: "a", Local: ChangeModified}},
+		},
+		{
+			name:   "Test modified on server",
+			local:  []localFile{{Path: "a", Hash: "1"}},
+			remote: []api.FileResponse{{Path: "a", Hash: "2"}},
+			index:  synced("a"),
+			want:   []change{{Path: "a", Remote: ChangeModified}},
+		},
+		{
+			name:   "Test deleted locally",
+			remote: []api.FileResponse{{Path: "a", Hash: "1"}},
+			index:  synced("a"),
+			want:   []change{{Path: "a", Local: ChangeDeleted}},
+		},
+		{
+			name:  "Test deleted on server",
+			local: []localFile{{Path: "a", Hash: "1"}},
+			index: synced("a"),
+			want:  []change{{Path: "a", Remote: ChangeDeleted}},
+		},
+		{
+			name:  "Test deleted on both sides",
+			index: synced("a"),
+		},
+		{
+			name:   "Test modified on both sides",
+			local:  []localFile{{Path: "a", Hash: "2"}},
+			remote: []api.FileResponse{{Path: "a", Hash: "3"}},
+			index:  synced("a"),
+			want:   []change{{Path: "a", Local: ChangeModified, Remote: ChangeModified}},
+		},
+		{
+			name:  "Test modified locally and deleted on server",
+			local: []localFile{{Path: "a", Hash: "2"}},
+			index: synced("a"),
+			want:  []change{{Path: "a", Local: ChangeModified, Remote: ChangeDeleted}},
+		},
+		{
+			name:   "Test same change on both sides",
+			local:  []localFile{{Path: "a", Hash: "2"}},
+			remote: []api.FileResponse{{Path: "a", Hash: "2"}},
+			index:  synced("a"),
+		},
+		{
+			name:   "Test different files without index",
+			local:  []localFile{{Path: "a", Hash: "2"}},
+			remote: []api.FileResponse{{Path: "a", Hash: "3"}},
+			want:   []change{{Path: "a", Local: ChangeAdded, Remote: ChangeAdded}},
+		},
+		{
+			name:   "Test directories are not compared",
+			local:  []localFile{{Path: "d", IsDir: true}},
+			remote: []api.FileResponse{{Path: "e", FileType: api.Dir}},
+			index:  synced(),
+		},
+		{
+			name:  "Test sorted by path",
+			local: []localFile{{Path: "b", Hash: "1"}, {Path: "a/c", Hash: "1"}},
+			index: synced(),
+			want:  []change{{Path: "a/c", Local: ChangeAdded}, {Path: "b", Local: ChangeAdded}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := map[string]localFile{}
+			for _, f := range tt.local {
+				local[f.Path] = f
+			}
+			var got []change
+			for _, c := range diffTrees(local, remoteFilesByPath(tt.remote, nil), tt.index) {
+				assert.Equal(t, c.LocalChange != ChangeNone && c.RemoteChange != ChangeNone, c.IsConflict())
+				got = append(got, change{Path: c.Path, Local: c.LocalChange, Remote: c.RemoteChange})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
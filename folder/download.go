@@ -0,0 +1,199 @@
+package folder
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+	"path/filepath"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDownloadJobs    = 4
+	DefaultDownloadRetries = 3
+	downloadRetryDelay     = 500 * time.Millisecond
+)
+
+type DownloadOptions struct {
+	Jobs    int
+	Retries int
+	// Resume skips files whose local copy already has the expected hash
+	Resume bool
+}
+
+type DownloadFailure = struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+type DownloadSummary = struct {
+	Files       int               `json:"files"`
+	Directories int               `json:"directories"`
+	Downloaded  int               `json:"downloaded"`
+	Skipped     int               `json:"skipped"`
+	Bytes       uint64            `json:"bytes"`
+	Failed      []DownloadFailure `json:"failed"`
+}
+
+// progressCounter tracks the bytes of a single attempt, so they can be taken back from the bar on failure
+type progressCounter struct {
+	progress *helpers.Progress
+	written  int64
+}
+
+func (c *progressCounter) Write(b []byte) (int, error) {
+	c.written += int64(len(b))
+	return c.progress.Write(b)
+}
+
+func isRetryableError(err error) bool {
+	return helpers.GetExitCode(err) == constants.ExitNetwork
+}
+
+func isDownloaded(file api.FileResponse, target string) bool {
+	if !helpers.IsExists(target) {
+		return false
+	}
+	hash, err := helpers.HashFile(target)
+	return err == nil && hash == file.Hash
+}
+
+// setModTime keeps the server modification time on the local copy
+func setModTime(target string, file api.FileResponse) error {
+	if file.UpdatedAt == 0 {
+		return nil
+	}
+	return os.Chtimes(target, file.UpdatedTime(), file.UpdatedTime())
+}
+
+func downloadFile(folderId string, file api.FileResponse, target string, accessToken string, retries int, progress *helpers.Progress) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return helpers.FailureError("Can't create directory for %s: %s", file.Path, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		counter := &progressCounter{progress: progress}
+		err := api.FolderFileDownload(folderId, file.Path, file.Hash, file.Size, accessToken, target, counter)
+		if err == nil {
+			return setModTime(target, file)
+		}
+		progress.Add(-counter.written)
+		if attempt >= retries || !isRetryableError(err) {
+			return err
+		}
+		progress.Log(fmt.Sprintf("Retrying %s (%d/%d): %s", file.Path, attempt+1, retries, err))
+		time.Sleep(downloadRetryDelay << attempt)
+	}
+}
+
+// createDirectories materializes DIR entries, it runs after the files are downloaded,
+// so the modification times are not changed again by creating their children
+func createDirectories(root string, dirs []api.FileResponse, summary *DownloadSummary) {
+	for _, dir := range dirs {
+		target, err := helpers.SafeJoin(root, dir.Path)
+		if err == nil {
+			err = os.MkdirAll(target, os.ModePerm)
+		}
+		if err != nil {
+			summary.Failed = append(summary.Failed, DownloadFailure{Path: dir.Path, Error: err.Error()})
+		}
+	}
+	for _, dir := range dirs {
+		if target, err := helpers.SafeJoin(root, dir.Path); err == nil {
+			_ = setModTime(target, dir)
+		}
+	}
+}
+
+// downloadFiles fetches files of the folder into root using a bounded pool of workers.
+// Failed downloads are retried with exponential backoff and collected in the summary,
+// downloaded and skipped files are recorded in the index.
+func downloadFiles(folderId string, root string, accessToken string, entries []api.FileResponse, options DownloadOptions, index *config.Index) DownloadSummary {
+	var files, dirs []api.FileResponse
+	var total uint64
+	for _, f := range entries {
+		if f.FileType == api.Dir {
+			dirs = append(dirs, f)
+			continue
+		}
+		files = append(files, f)
+		total += f.Size
+	}
+	summary := DownloadSummary{Files: len(files), Directories: len(dirs), Failed: []DownloadFailure{}}
+
+	progress := helpers.NewProgress("Downloading", len(files), total)
+	defer progress.Finish()
+
+	jobs := options.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan api.FileResponse)
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range queue {
+				target, err := helpers.SafeJoin(root, file.Path)
+				if err != nil {
+					progress.FileDone()
+					mutex.Lock()
+					summary.Failed = append(summary.Failed, DownloadFailure{Path: file.Path, Error: err.Error()})
+					mutex.Unlock()
+					continue
+				}
+
+				if options.Resume && isDownloaded(file, target) {
+					progress.Add(int64(file.Size))
+					progress.FileDone()
+					mutex.Lock()
+					summary.Skipped++
+					_ = index.Set(helpers.NormalizePath(file.Path), target, file.Hash, file.SherryFileID, file.UpdatedAt)
+					mutex.Unlock()
+					continue
+				}
+
+				err = downloadFile(folderId, file, target, accessToken, options.Retries, progress)
+				progress.FileDone()
+
+				mutex.Lock()
+				if err != nil {
+					summary.Failed = append(summary.Failed, DownloadFailure{Path: file.Path, Error: err.Error()})
+				} else {
+					summary.Downloaded++
+					summary.Bytes += file.Size
+					_ = index.Set(helpers.NormalizePath(file.Path), target, file.Hash, file.SherryFileID, file.UpdatedAt)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, f := range files {
+		queue <- f
+	}
+	close(queue)
+	wg.Wait()
+
+	createDirectories(root, dirs, &summary)
+	return summary
+}
+
+func printDownloadSummary(summary DownloadSummary) {
+	helpers.PrintMessage(fmt.Sprintf(
+		"Downloaded %d of %d files (%s), %d skipped, %d failed",
+		summary.Downloaded, summary.Files, humanize.Bytes(summary.Bytes), summary.Skipped, len(summary.Failed),
+	))
+	for _, f := range summary.Failed {
+		helpers.PrintErr(fmt.Sprintf("  %s: %s", f.Path, f.Error))
+	}
+}
@@ -0,0 +1,67 @@
+package folder
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sync"
+	"testing"
+)
+
+func TestDownloadFiles(t *testing.T) {
+	hashOf := func(content string) string {
+		h := helpers.NewHash()
+		h.Write([]byte(content))
+		return helpers.HashSum(h)
+	}
+	contents := map[string]string{"a.txt": "kept", "b.txt": "corrupted", "c.txt": "downloaded"}
+	served := map[string]string{"a.txt": "kept", "b.txt": "c0rrupted", "c.txt": "downloaded"}
+
+	var mutex sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Query().Get("path")
+		mutex.Lock()
+		requests[p]++
+		mutex.Unlock()
+		_, _ = w.Write([]byte(served[p]))
+	}))
+	defer server.Close()
+
+	configDir := t.TempDir()
+	writeJson := func(name string, v interface{}) {
+		data, _ := json.Marshal(v)
+		assert.NoError(t, os.WriteFile(filepath.Join(configDir, name), data, 0644))
+	}
+	writeJson(constants.ConfigFile, config.Config{ApiUrl: server.URL})
+	writeJson(constants.AuthConfigFile, config.AuthorizationConfig{Sources: map[string]config.Credentials{}})
+	assert.NoError(t, config.SetupConfig(configDir))
+
+	root := t.TempDir()
+	// A previous interrupted download left a.txt complete
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("kept"), 0644))
+
+	var entries []api.FileResponse
+	for _, p := range []string{"a.txt", "b.txt", "c.txt"} {
+		entries = append(entries, api.FileResponse{Path: p, Hash: hashOf(contents[p]), Size: uint64(len(contents[p])), FileType: api.File})
+	}
+	index := config.NewIndex()
+	summary := downloadFiles("folder-1", root, "", entries, DownloadOptions{Jobs: 2, Retries: 1, Resume: true}, index)
+
+	assert.Equal(t, 1, summary.Skipped)
+	assert.Equal(t, 1, summary.Downloaded)
+	assert.Equal(t, []DownloadFailure{{Path: "b.txt", Error: "Downloaded b.txt has hash " + hashOf("c0rrupted") + ", expected " + hashOf("corrupted")}}, summary.Failed)
+	assert.Equal(t, map[string]int{"b.txt": 2, "c.txt": 1}, requests)
+
+	assert.NoFileExists(t, filepath.Join(root, "b.txt"))
+	assert.Contains(t, index.Files, "a.txt")
+	assert.Contains(t, index.Files, "c.txt")
+	assert.NotContains(t, index.Files, "b.txt")
+}
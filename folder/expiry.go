@@ -0,0 +1,151 @@
+package folder
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sort"
+	"time"
+)
+
+type SweepEntry = struct {
+	Folder    string    `json:"folder"`
+	FolderId  string    `json:"folderId"`
+	UserId    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type SweepResult = struct {
+	DryRun  bool         `json:"dryRun"`
+	Revoked []SweepEntry `json:"revoked"`
+	Failed  int          `json:"failed"`
+}
+
+// forgetExpiry drops the expiry of a permission which was revoked or granted again without expiry
+func forgetExpiry(folderId string, userId string) error {
+	return config.UpdateExpiryLedger(func(ledger *config.ExpiryLedger) {
+		ledger.Remove(folderId, userId)
+	})
+}
+
+// resolveRequestExpiry drops the expiry of the requester when the request is approved, the approved role replaces
+// the grant, a denied request keeps the current grant with its expiry
+func resolveRequestExpiry(ledger *config.ExpiryLedger, request AccessRequestEntry, approve bool) {
+	if approve {
+		ledger.Remove(request.FolderId, request.UserId)
+	}
+}
+
+func formatExpiry(expiresAt time.Time) string {
+	if expiresAt.Before(time.Now()) {
+		return fmt.Sprintf("expired %s", humanize.Time(expiresAt))
+	}
+	return fmt.Sprintf("expires %s", humanize.Time(expiresAt))
+}
+
+// expiredGrants returns grants expired at now, only the ones granted by grantedBy if it is set
+func expiredGrants(ledger *config.ExpiryLedger, now time.Time, grantedBy string) []config.ExpiringGrant {
+	grants := helpers.Filter(ledger.Grants, func(g config.ExpiringGrant) bool {
+		return !g.ExpiresAt.After(now) && (grantedBy == "" || g.GrantedBy == grantedBy)
+	})
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].ExpiresAt.Before(grants[j].ExpiresAt)
+	})
+	return grants
+}
+
+func revokeExpiredGrant(grant config.ExpiringGrant) error {
+	credentials, err := auth.GetActiveUserById(grant.GrantedBy)
+	if err != nil {
+		return err
+	}
+	err = api.FolderPermissionRevoke(grant.FolderId, grant.UserId, credentials.AccessToken)
+	// The folder was deleted or the access was already revoked
+	if err != nil && helpers.GetExitCode(err) == constants.ExitNotFound {
+		return nil
+	}
+	return err
+}
+
+func printSweepResult(result SweepResult) {
+	if len(result.Revoked) == 0 {
+		helpers.PrintMessage("No expired permissions")
+		return
+	}
+	for _, e := range result.Revoked {
+		line := fmt.Sprintf("  revoke %s %s %s (%s)", e.Folder, e.Username, e.Role, formatExpiry(e.ExpiresAt))
+		if e.Error != "" {
+			line = helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, fmt.Sprintf("%s: %s", line, e.Error))
+		}
+		helpers.PrintMessage(line)
+	}
+	if result.DryRun {
+		helpers.PrintMessage(fmt.Sprintf("%d permissions expired, nothing was changed", len(result.Revoked)))
+	} else {
+		helpers.PrintMessage(fmt.Sprintf("%d permissions revoked, %d failed", len(result.Revoked)-result.Failed, result.Failed))
+	}
+}
+
+// SweepPermissions revokes expired permissions with credentials of the users who granted them
+func SweepPermissions(user string, dryRun bool) (bool, error) {
+	grantedBy := ""
+	if user != "" {
+		credentials, err := auth.FindActiveUser(user)
+		if err != nil {
+			return false, err
+		}
+		grantedBy = credentials.UserId
+	}
+
+	ledger, err := config.ReadExpiryLedger()
+	if err != nil {
+		return false, err
+	}
+
+	result := SweepResult{DryRun: dryRun, Revoked: []SweepEntry{}}
+	var firstErr error
+	for _, grant := range expiredGrants(ledger, time.Now(), grantedBy) {
+		entry := SweepEntry{
+			Folder:    grant.Folder,
+			FolderId:  grant.FolderId,
+			UserId:    grant.UserId,
+			Username:  grant.Username,
+			Role:      grant.Role,
+			ExpiresAt: grant.ExpiresAt,
+		}
+		if !dryRun {
+			if err := revokeExpiredGrant(grant); err != nil {
+				entry.Error = err.Error()
+				result.Failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			} else {
+				ledger.Remove(grant.FolderId, grant.UserId)
+			}
+		}
+		result.Revoked = append(result.Revoked, entry)
+	}
+
+	if !dryRun {
+		if err := config.CommitExpiryLedger(ledger); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	helpers.PrintResult(result, func() {
+		printSweepResult(result)
+	})
+
+	if firstErr != nil {
+		return false, helpers.WrapError(helpers.GetExitCode(firstErr), firstErr, fmt.Sprintf("%d of %d permissions were not revoked", result.Failed, len(result.Revoked)))
+	}
+	return false, nil
+}
@@ -13,6 +13,86 @@ type Options struct {
 	Permissions PermissionOptions `command:"permission" description:"Manage shared folder access"`
 	List        ListOptions       `command:"list" description:"List folders"`
 	Unwatch     UnwatchOptions    `command:"unwatch" description:"Unwatch folder"`
+	Sync        SyncOptions       `command:"sync" description:"Synchronize watched folder with the server once"`
+	Status      StatusOptions     `command:"status" description:"Show local and remote changes of watched folder"`
+	Check       CheckOptions      `command:"check" description:"Check files of watched folder against the folder rules"`
+	Ignore      IgnoreOptions     `command:"ignore" description:"Manage ignored files of watched folder"`
+	Delete      DeleteOptions     `command:"delete" description:"Delete shared folder on the server"`
+	Rename      RenameOptions     `command:"rename" description:"Rename shared folder"`
+	Request     RequestOptions    `command:"request-access" description:"Ask the owner for access to shared folder"`
+	Transfer    TransferOptions   `command:"transfer" description:"Transfer shared folder ownership to another user"`
+}
+
+type TransferOptions struct {
+	User string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Yes  bool   `long:"yes" short:"y" description:"Skip confirmation"`
+	To   string `long:"to" required:"yes" description:"Username or id of the new owner"`
+	Keep string `long:"keep" choice:"write" choice:"read" choice:"none" default:"write" description:"Role you keep after the transfer"`
+	Args struct {
+		Folder string `positional-arg-name:"folder" description:"Shared folder name or id"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type RequestOptions struct {
+	User string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Role string `long:"role" description:"Permission role (read/write)"`
+	Args struct {
+		Folder string `positional-arg-name:"folder" description:"Shared folder in format owner_username:folder_name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type RenameOptions struct {
+	User string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Args struct {
+		Name    string `positional-arg-name:"folder" description:"Shared folder name"`
+		NewName string `positional-arg-name:"new-name" description:"New shared folder name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type DeleteOptions struct {
+	User    string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Confirm string `long:"confirm" description:"Folder name to confirm deletion without prompting"`
+	Args    struct {
+		Folder string `positional-arg-name:"folder" description:"Shared folder name or id"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type IgnoreEditOptions struct {
+	Path  flag.Filename `long:"path" short:"p" description:"Path inside watched folder (current directory by default)"`
+	Local bool          `long:"local" short:"l" description:"Edit patterns of this machine only instead of the shared .sherryignore file"`
+	Args  struct {
+		Patterns []string `positional-arg-name:"pattern" description:"Gitignore style patterns"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type IgnoreListOptions struct {
+	Path flag.Filename `long:"path" short:"p" description:"Path inside watched folder (current directory by default)"`
+}
+
+type IgnoreOptions struct {
+	Add    IgnoreEditOptions `command:"add" description:"Add ignore patterns"`
+	List   IgnoreListOptions `command:"list" description:"List ignore patterns"`
+	Remove IgnoreEditOptions `command:"remove" description:"Remove ignore patterns"`
+}
+
+type CheckOptions struct {
+	Args struct {
+		Path flag.Filename `positional-arg-name:"path" description:"Path inside watched folder (current directory by default)"`
+	} `positional-args:"yes"`
+}
+
+type StatusOptions struct {
+	Args struct {
+		Path flag.Filename `positional-arg-name:"path" description:"Path inside watched folder (current directory by default)"`
+	} `positional-args:"yes"`
+}
+
+type SyncOptions struct {
+	DryRun bool   `long:"dry-run" description:"Only print the planned operations"`
+	Prefer string `long:"prefer" choice:"local" choice:"remote" description:"Mirror one side, deleting files missing on it, instead of merging both"`
+	Args   struct {
+		Path flag.Filename `positional-arg-name:"path" description:"Path inside watched folder (current directory by default)"`
+	} `positional-args:"yes"`
 }
 
 type UnwatchOptions struct {
@@ -29,21 +109,64 @@ type ListOptions struct {
 }
 
 type PermissionGrantOptions struct {
-	User   string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
-	Target string `long:"target" short:"t" description:"Username or id of user to manage access for"`
-	Role   string `long:"role" description:"Permission role (read/write)"`
-	Name   string `long:"name" short:"n" description:"Shared folder name"`
+	User    string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Target  string `long:"target" short:"t" description:"Username or id of user to manage access for, or @group for every member of a local group"`
+	Role    string `long:"role" description:"Permission role (read/write)"`
+	Name    string `long:"name" short:"n" description:"Shared folder name"`
+	Expires string `long:"expires" description:"Revoke the permission after this duration, e.g. 12h, 7d or 2w (see permission sweep)"`
+}
+
+type PermissionSweepOptions struct {
+	User   string `long:"user" short:"u" description:"Only revoke permissions granted by this user profile"`
+	DryRun bool   `long:"dry-run" description:"Only print the expired permissions"`
 }
 
 type PermissionRevokeOptions struct {
 	User   string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
-	Target string `long:"target" short:"t" description:"Username or id of user to manage access for"`
+	Target string `long:"target" short:"t" description:"Username or id of user to manage access for, or @group for every member of a local group"`
 	Name   string `long:"name" short:"n" description:"Shared folder name"`
 }
 
+type PermissionListOptions struct {
+	User string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Args struct {
+		Folder string `positional-arg-name:"folder" description:"Shared folder name, owner_username:folder_name or folder id"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type PermissionApplyOptions struct {
+	User   string        `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	File   flag.Filename `long:"file" short:"f" required:"yes" description:"YAML file with the users and roles per folder"`
+	DryRun bool          `long:"dry-run" description:"Only print the planned changes"`
+	Prune  bool          `long:"prune" description:"Revoke access of users not listed in the file"`
+}
+
+type PermissionPendingOptions struct {
+	User string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Name string `long:"name" short:"n" description:"Only show requests for this shared folder"`
+}
+
+type PermissionResolveOptions struct {
+	User string `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Args struct {
+		Id string `positional-arg-name:"request-id" description:"Access request id"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type PermissionApproveOptions struct {
+	PermissionResolveOptions
+	Role string `long:"role" description:"Grant this role instead of the requested one (read/write)"`
+}
+
 type PermissionOptions struct {
-	Grant  PermissionGrantOptions  `command:"grant" description:"Grant access to shared folder"`
-	Revoke PermissionRevokeOptions `command:"revoke" description:"Revoke access from shared folder"`
+	Grant   PermissionGrantOptions   `command:"grant" description:"Grant access to shared folder"`
+	Revoke  PermissionRevokeOptions  `command:"revoke" description:"Revoke access from shared folder"`
+	List    PermissionListOptions    `command:"list" description:"List users with access to shared folder"`
+	Apply   PermissionApplyOptions   `command:"apply" description:"Apply users and roles declared in a file"`
+	Pending PermissionPendingOptions `command:"pending" description:"List pending access requests to your folders"`
+	Approve PermissionApproveOptions `command:"approve" description:"Approve access request"`
+	Deny    PermissionResolveOptions `command:"deny" description:"Deny access request"`
+	Sweep   PermissionSweepOptions   `command:"sweep" description:"Revoke expired permissions"`
 }
 
 type CreateOptions struct {
@@ -55,10 +178,13 @@ type CreateOptions struct {
 }
 
 type GetOptions struct {
-	Path flag.Filename `long:"path" short:"p" description:"Specify local path for operation"`
-	User string        `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
-	Yes  bool          `long:"yes" short:"y" description:"Skip confirmation and use default values where possible"`
-	Args struct {
+	Path    flag.Filename `long:"path" short:"p" description:"Specify local path for operation"`
+	User    string        `long:"user" short:"u" description:"Use specific user profile for operation (Default profile will be used if no specified)"`
+	Yes     bool          `long:"yes" short:"y" description:"Skip confirmation and use default values where possible"`
+	Jobs    int           `long:"jobs" short:"j" default:"4" description:"Number of parallel downloads"`
+	Retries int           `long:"retries" default:"3" description:"Number of retries for a failed download"`
+	Resume  bool          `long:"resume" description:"Continue download into existing directory, files with matching hash are skipped"`
+	Args    struct {
 		Folder string `positional-arg-name:"folder"  description:"Shared folder in format owner_username:folder_name or folder id"`
 	} `positional-args:"yes" required:"yes" description:"Shared folder in format owner_username:folder_name or folder id"`
 }
@@ -78,36 +204,77 @@ type UpdateOptions struct {
 	} `positional-args:"yes" required:"yes" description:"Shared folder name"`
 }
 
-func ApplyCommands(cmd *flag.Command, options Options) {
+func ApplyCommands(cmd *flag.Command, options Options) error {
 	if cmd.Active.Name != "folder" {
-		return
+		return nil
 	}
 
-	config.WithCommit(func() bool {
+	return config.WithCommit(func() (bool, error) {
 		switch cmd.Active.Active.Name {
 		case "create":
 			return CreateSharedFolder(options.Create.User, options.Create.Yes, string(options.Create.Path), options.Create.Name, options.Create.Set)
 		case "get":
-			return GetSharedFolder(options.Get.User, options.Get.Yes, string(options.Get.Path), options.Get.Args.Folder)
+			return GetSharedFolder(options.Get.User, options.Get.Yes, string(options.Get.Path), options.Get.Args.Folder, DownloadOptions{
+				Jobs:    options.Get.Jobs,
+				Retries: options.Get.Retries,
+				Resume:  options.Get.Resume,
+			})
 		case "show":
 			return DisplaySharedFolder(options.Show.User, options.Show.Args.Name)
 		case "update":
 			return UpdateSharedFolder(options.Update.User, options.Update.Args.Name, options.Update.Set)
 		case "unwatch":
 			return UnwatchSharedFolder(string(options.Unwatch.Args.Path), options.Unwatch.Yes, options.Unwatch.Force)
+		case "sync":
+			return SyncSharedFolder(string(options.Sync.Args.Path), options.Sync.DryRun, options.Sync.Prefer)
+		case "check":
+			return CheckSharedFolder(string(options.Check.Args.Path))
+		case "status":
+			return StatusSharedFolder(string(options.Status.Args.Path))
 		case "list":
 			return ListSharedFolders(options.List.User, options.List.Available)
+		case "request-access":
+			return RequestAccess(options.Request.User, options.Request.Args.Folder, options.Request.Role)
+		case "transfer":
+			return TransferSharedFolder(options.Transfer.User, options.Transfer.Yes, options.Transfer.Args.Folder, options.Transfer.To, options.Transfer.Keep)
+		case "rename":
+			return RenameSharedFolder(options.Rename.User, options.Rename.Args.Name, options.Rename.Args.NewName)
+		case "delete":
+			return DeleteSharedFolder(options.Delete.User, options.Delete.Args.Folder, options.Delete.Confirm)
+		case "ignore":
+			switch cmd.Active.Active.Active.Name {
+			case "add":
+				return AddIgnorePatterns(string(options.Ignore.Add.Path), options.Ignore.Add.Args.Patterns, options.Ignore.Add.Local)
+			case "list":
+				return ListIgnorePatterns(string(options.Ignore.List.Path))
+			case "remove":
+				return RemoveIgnorePatterns(string(options.Ignore.Remove.Path), options.Ignore.Remove.Args.Patterns, options.Ignore.Remove.Local)
+			default:
+				return false, nil
+			}
 		case "permission":
 			switch cmd.Active.Active.Active.Name {
 			case "grant":
-				return GrantPermission(options.Permissions.Grant.User, options.Permissions.Grant.Target, options.Permissions.Grant.Name, options.Permissions.Grant.Role)
+				return GrantPermission(options.Permissions.Grant.User, options.Permissions.Grant.Target, options.Permissions.Grant.Name, options.Permissions.Grant.Role, options.Permissions.Grant.Expires)
+			case "sweep":
+				return SweepPermissions(options.Permissions.Sweep.User, options.Permissions.Sweep.DryRun)
 			case "revoke":
 				return RevokePermission(options.Permissions.Revoke.User, options.Permissions.Revoke.Target, options.Permissions.Revoke.Name)
+			case "list":
+				return ListPermissions(options.Permissions.List.User, options.Permissions.List.Args.Folder)
+			case "pending":
+				return ListPendingRequests(options.Permissions.Pending.User, options.Permissions.Pending.Name)
+			case "approve":
+				return ResolveAccessRequest(options.Permissions.Approve.User, options.Permissions.Approve.Args.Id, true, options.Permissions.Approve.Role)
+			case "deny":
+				return ResolveAccessRequest(options.Permissions.Deny.User, options.Permissions.Deny.Args.Id, false, "")
+			case "apply":
+				return ApplyPermissions(options.Permissions.Apply.User, string(options.Permissions.Apply.File), options.Permissions.Apply.DryRun, options.Permissions.Apply.Prune)
 			default:
-				return false
+				return false, nil
 			}
 		default:
-			return false
+			return false, nil
 		}
 	})
 }
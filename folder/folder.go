@@ -1,7 +1,6 @@
 package folder
 
 import (
-	"errors"
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"github.com/erikgeiser/promptkit/confirmation"
@@ -12,7 +11,9 @@ import (
 	"sherry/shr/auth"
 	"sherry/shr/config"
 	"sherry/shr/constants"
+	"sherry/shr/group"
 	"sherry/shr/helpers"
+	"sort"
 	"strings"
 	"time"
 )
@@ -36,6 +37,40 @@ type Params = struct {
 	Path string `json:"path"`
 }
 
+type Result = struct {
+	Source   config.Source    `json:"source"`
+	Watcher  *config.Watcher  `json:"watcher,omitempty"`
+	Download *DownloadSummary `json:"download,omitempty"`
+}
+
+type SourceResult = struct {
+	config.Source
+	Watchers []string `json:"watchers"`
+}
+
+type UserFoldersResult = struct {
+	User    auth.UserInfo  `json:"user"`
+	Folders []SourceResult `json:"folders"`
+}
+
+type ShowResult = struct {
+	config.Source
+	Owner string   `json:"owner"`
+	Clone []string `json:"clone"`
+}
+
+type PermissionResult = struct {
+	Folder   string `json:"folder"`
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+	// ExpiresAt is set for grants revoked by the permission sweep
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
 type PermissionParams = struct {
 	Target string `json:"target"`
 	Name   string `json:"name"`
@@ -52,89 +87,128 @@ func prepareSettings(settings map[string]string) map[string]string {
 	return s
 }
 
-func getFolderSettings(yes bool, settings map[string]string) SourceSettings {
+func getFolderSettings(yes bool, settings map[string]string) (*SourceSettings, error) {
 	settings = prepareSettings(settings)
 
-	if yes {
-		return SourceSettings{
-			AllowDir:         helpers.ParseBool("Allow directory", settings["allowDir"], constants.DefaultAllowDir),
-			MaxFileSize:      helpers.ParseDataSize("Max file size", settings["maxFileSize"], constants.DefaultMaxFileSize, constants.MaxFileSize),
-			MaxDirSize:       helpers.ParseDataSize("Max directory size", settings["maxDirSize"], constants.DefaultMaxDirSize, constants.MaxDirSize),
-			AllowedFileNames: helpers.ParseValueArray("Allowed file names", settings["allowedFileNames"], helpers.IsGlobValidator, ""),
-			AllowedFileTypes: helpers.ParseValueArray("Allowed file types", settings["allowedFileTypes"], helpers.IsMimeTypeValidator, ""),
+	var result SourceSettings
+	var err error
+
+	// Settings have defaults, so there is nothing to prompt for without a terminal
+	if yes || !helpers.IsInteractive() {
+		if result.AllowDir, err = helpers.ParseBool("Allow directory", settings["allowDir"], constants.DefaultAllowDir); err != nil {
+			return nil, err
+		}
+		if result.MaxFileSize, err = helpers.ParseDataSize("Max file size", settings["maxFileSize"], constants.DefaultMaxFileSize, constants.MaxFileSize); err != nil {
+			return nil, err
+		}
+		if result.MaxDirSize, err = helpers.ParseDataSize("Max directory size", settings["maxDirSize"], constants.DefaultMaxDirSize, constants.MaxDirSize); err != nil {
+			return nil, err
 		}
+		if result.AllowedFileNames, err = helpers.ParseValueArray("Allowed file names", settings["allowedFileNames"], helpers.IsGlobValidator, ""); err != nil {
+			return nil, err
+		}
+		if result.AllowedFileTypes, err = helpers.ParseValueArray("Allowed file types", settings["allowedFileTypes"], helpers.IsMimeTypeValidator, ""); err != nil {
+			return nil, err
+		}
+		return &result, nil
 	}
 
 	defaultAllowDir := new(bool)
 	*defaultAllowDir = constants.DefaultAllowDir
 
-	return SourceSettings{
-		AllowDir: helpers.Confirmation("Allow directory", settings["allowDir"], defaultAllowDir),
-		MaxFileSize: helpers.ParseDataSize(
-			"Max file size",
-			helpers.Input(
-				"Max file size",
-				settings["maxFileSize"],
-				helpers.GetDataSizeValidator(constants.MaxFileSize),
-				fmt.Sprintf("Up to %s", humanize.Bytes(constants.MaxFileSize)),
-				false,
-			),
-			constants.DefaultMaxFileSize,
-			constants.MaxFileSize,
-		),
-		MaxDirSize: helpers.ParseDataSize(
-			"Max directory size",
-			helpers.Input(
-				"Max directory size",
-				settings["maxDirSize"],
-				helpers.GetDataSizeValidator(constants.MaxDirSize),
-				fmt.Sprintf("Up to %s", humanize.Bytes(constants.MaxDirSize)),
-				false,
-			),
-			constants.DefaultMaxDirSize,
-			constants.MaxDirSize,
-		),
-		AllowedFileNames: helpers.ParseValueArray(
-			"Allowed file names",
-			helpers.Input(
-				"Allowed file names",
-				settings["allowedFileNames"],
-				helpers.GetValidValueArrayValidator(true, helpers.IsGlobValidator),
-				"Glob patterns separated by commas",
-				false,
-			),
-			helpers.IsGlobValidator,
-			"",
-		),
-		AllowedFileTypes: helpers.ParseValueArray(
-			"Allowed file types",
-			helpers.Input(
-				"Allowed file types",
-				settings["allowedFileTypes"],
-				helpers.GetValidValueArrayValidator(true, helpers.IsMimeTypeValidator),
-				"MIME types separated by commas",
-				false,
-			),
-			helpers.IsMimeTypeValidator,
-			"",
-		),
-	}
-}
-
-func getFolderInfo(yes bool, path string, name string, settings map[string]string) Info {
-	return Info{
-		Name: helpers.If(yes, func() string {
-			return name
-		}, func() string {
-			return helpers.Input("Name", name, helpers.IsWordValidator, "", false)
-		}),
-		Path:     helpers.Input("Path", path, helpers.IsPathValidator, "", false),
-		Settings: getFolderSettings(yes, settings),
+	if result.AllowDir, err = helpers.Confirmation("Allow directory", "--set allowDir=<bool>", settings["allowDir"], defaultAllowDir); err != nil {
+		return nil, err
+	}
+
+	maxFileSize, err := helpers.Input(
+		"Max file size",
+		"--set maxFileSize=<value>",
+		settings["maxFileSize"],
+		helpers.GetDataSizeValidator(constants.MaxFileSize),
+		fmt.Sprintf("Up to %s", humanize.Bytes(constants.MaxFileSize)),
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MaxFileSize, err = helpers.ParseDataSize("Max file size", maxFileSize, constants.DefaultMaxFileSize, constants.MaxFileSize); err != nil {
+		return nil, err
+	}
+
+	maxDirSize, err := helpers.Input(
+		"Max directory size",
+		"--set maxDirSize=<value>",
+		settings["maxDirSize"],
+		helpers.GetDataSizeValidator(constants.MaxDirSize),
+		fmt.Sprintf("Up to %s", humanize.Bytes(constants.MaxDirSize)),
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MaxDirSize, err = helpers.ParseDataSize("Max directory size", maxDirSize, constants.DefaultMaxDirSize, constants.MaxDirSize); err != nil {
+		return nil, err
+	}
+
+	allowedFileNames, err := helpers.Input(
+		"Allowed file names",
+		"--set allowedFileNames=<value>",
+		settings["allowedFileNames"],
+		helpers.GetValidValueArrayValidator(true, helpers.IsGlobValidator),
+		"Glob patterns separated by commas",
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.AllowedFileNames, err = helpers.ParseValueArray("Allowed file names", allowedFileNames, helpers.IsGlobValidator, ""); err != nil {
+		return nil, err
+	}
+
+	allowedFileTypes, err := helpers.Input(
+		"Allowed file types",
+		"--set allowedFileTypes=<value>",
+		settings["allowedFileTypes"],
+		helpers.GetValidValueArrayValidator(true, helpers.IsMimeTypeValidator),
+		"MIME types separated by commas",
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.AllowedFileTypes, err = helpers.ParseValueArray("Allowed file types", allowedFileTypes, helpers.IsMimeTypeValidator, ""); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func getFolderInfo(yes bool, path string, name string, settings map[string]string) (*Info, error) {
+	var err error
+	if !yes {
+		if name, err = helpers.Input("Name", "--name", name, helpers.IsWordValidator, "", false); err != nil {
+			return nil, err
+		}
+	}
+	if path, err = helpers.Input("Path", "--path", path, helpers.IsPathValidator, "", false); err != nil {
+		return nil, err
+	}
+	folderSettings, err := getFolderSettings(yes, settings)
+	if err != nil {
+		return nil, err
 	}
+	return &Info{
+		Name:     name,
+		Path:     path,
+		Settings: *folderSettings,
+	}, nil
 }
 
-func getFolderParams(yes bool, p string, name string) Params {
-	name = helpers.Input("Folder name in format owner_username:folder_name or id", name, helpers.IsUsernameFolderOrIdValidator, "", false)
+func getFolderParams(yes bool, p string, name string) (*Params, error) {
+	name, err := helpers.Input("Folder name in format owner_username:folder_name or id", "folder argument", name, helpers.IsUsernameFolderOrIdValidator, "", false)
+	if err != nil {
+		return nil, err
+	}
 
 	if yes && p == "" {
 		p = path.Join(".", helpers.If(helpers.IsUsernameFolder(name) == nil, func() string {
@@ -144,22 +218,36 @@ func getFolderParams(yes bool, p string, name string) Params {
 		}))
 	}
 
-	return Params{
-		Name: name,
-		Path: helpers.Input("Path", p, helpers.IsPathValidator, "", false),
+	if p, err = helpers.Input("Path", "--path", p, helpers.IsPathValidator, "", false); err != nil {
+		return nil, err
 	}
+
+	return &Params{
+		Name: name,
+		Path: p,
+	}, nil
 }
 
-func getFolderPermissionsParams(target, name, role string, withRole bool) PermissionParams {
-	return PermissionParams{
-		Name:   helpers.Input("Folder Name", name, helpers.IsWordValidator, "", false),
-		Target: helpers.Input("Target username or id", target, helpers.IsWordOrIdValidator, "", false),
-		Role: strings.ToUpper(helpers.If(withRole, func() string {
-			return helpers.Select("Role", strcase.ToCamel(role), []string{"Read", "Write"})
-		}, func() string {
-			return ""
-		})),
+func getFolderPermissionsParams(target, name, role string, withRole bool) (*PermissionParams, error) {
+	var err error
+	if name, err = helpers.Input("Folder Name", "--name", name, helpers.IsWordValidator, "", false); err != nil {
+		return nil, err
+	}
+	if target, err = helpers.Input("Target username, id or @group", "--target", target, group.IsTargetValidator, "", false); err != nil {
+		return nil, err
+	}
+	if withRole {
+		if role, err = helpers.Select("Role", "--role", strcase.ToCamel(role), []string{"Read", "Write"}); err != nil {
+			return nil, err
+		}
+	} else {
+		role = ""
 	}
+	return &PermissionParams{
+		Name:   name,
+		Target: target,
+		Role:   strings.ToUpper(role),
+	}, nil
 }
 
 func getAccessType(response *api.ResponseFolder, userId string) string {
@@ -209,56 +297,52 @@ func generateSourceId(userId, sherryId string) string {
 	return fmt.Sprintf("%s@%s", userId, sherryId)
 }
 
-func getAvailableSource(name string, credentials config.Credentials) *api.ResponseFolder {
+func getAvailableSource(name string, credentials config.Credentials) (*api.ResponseFolder, error) {
 	availableFolders, err := api.FolderGetAvailable(credentials.AccessToken)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return helpers.Find(*availableFolders, func(f api.ResponseFolder) bool {
+	source := helpers.Find(*availableFolders, func(f api.ResponseFolder) bool {
 		return f.Name == name && f.UserId == credentials.UserId
 	})
+	if source == nil {
+		return nil, helpers.NotFoundError("Folder is not available or not exists")
+	}
+	return source, nil
 }
 
-func CreateSharedFolder(user string, yes bool, path string, name string, settings map[string]string) bool {
-	credentials := auth.FindUserByUsername(user, true)
-
-	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
-	}
-	if credentials.Expired {
-		helpers.PrintErr("Your session has expired")
-		return false
+func CreateSharedFolder(user string, yes bool, path string, name string, settings map[string]string) (bool, error) {
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
 	}
 
-	folderInfo := getFolderInfo(yes, path, name, settings)
+	folderInfo, err := getFolderInfo(yes, path, name, settings)
+	if err != nil {
+		return false, err
+	}
 
 	path = helpers.PreparePath(folderInfo.Path)
 
 	for _, w := range config.GetConfig().Watchers {
 		isChild, err := helpers.IsChildPath(path, helpers.PreparePath(w.LocalPath))
 		if err != nil {
-			helpers.PrintErr("Error while checking path")
-			return false
+			return false, helpers.FailureError("Error while checking path")
 		}
 		if isChild {
-			helpers.PrintErr("Path is already being watched")
-			return false
+			return false, helpers.ConflictError("Path is already being watched")
 		}
 	}
 
 	stat, err := os.Stat(path)
 	if stat != nil && !stat.IsDir() {
-		helpers.PrintErr("Path is not a directory")
-		return false
+		return false, helpers.UsageError("Path is not a directory")
 	}
 	if os.IsNotExist(err) {
 		err := os.MkdirAll(path, os.ModePerm)
 		if err != nil {
-			helpers.PrintErr("Can't create directory")
-			helpers.PrintErr(err.Error())
-			return false
+			return false, helpers.FailureError("Can't create directory\n%s", err)
 		}
 	}
 
@@ -271,59 +355,57 @@ func CreateSharedFolder(user string, yes bool, path string, name string, setting
 		AllowedFileTypes: helpers.EmptyIfNull(folderInfo.Settings.AllowedFileTypes),
 	}, credentials.AccessToken)
 	if err != nil {
-		return false
+		return false, err
 	}
 
 	conf := config.GetConfig()
 	sourceId := generateSourceId(credentials.UserId, response.SherryId)
 	conf.Sources[sourceId] = responseToSource(response, credentials.UserId)
-	conf.Watchers = append(conf.Watchers, createWatcher(sourceId, credentials.UserId, response.SherryId, path, false))
+	watcher := createWatcher(sourceId, credentials.UserId, response.SherryId, path, false)
+	conf.Watchers = append(conf.Watchers, watcher)
 
-	helpers.PrintMessage(fmt.Sprintf("Sherry is created and watching at %s", path))
+	helpers.PrintResult(Result{Source: conf.Sources[sourceId], Watcher: &watcher}, func() {
+		helpers.PrintMessage(fmt.Sprintf("Sherry is created and watching at %s", path))
+	})
 
-	return true
+	return true, nil
 }
 
-func GetSharedFolder(user string, yes bool, localPath string, name string) bool {
-	credentials := auth.FindUserByUsername(user, true)
-
-	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
-	}
-	if credentials.Expired {
-		helpers.PrintErr("Your session has expired")
-		return false
+func GetSharedFolder(user string, yes bool, localPath string, name string, options DownloadOptions) (bool, error) {
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
 	}
 
-	folderParams := getFolderParams(yes, localPath, name)
+	folderParams, err := getFolderParams(yes, localPath, name)
+	if err != nil {
+		return false, err
+	}
 	localPath = helpers.PreparePath(folderParams.Path)
 
-	if helpers.IsExists(localPath) {
-		helpers.PrintErr("Directory already exists")
-		return false
+	if helpers.IsExists(localPath) && !options.Resume {
+		return false, helpers.ConflictError("Directory already exists, use --resume to continue an interrupted download")
 	}
 
 	var folderId string
 	if helpers.IsUsernameFolder(folderParams.Name) == nil {
 		availableFolders, err := api.FolderGetAvailable(credentials.AccessToken)
 		if err != nil {
-			return false
+			return false, err
 		}
 
 		args := strings.Split(folderParams.Name, ":")
 		folderName := args[1]
 		userData, err := api.UserFindByUsername(args[0], credentials.AccessToken)
 		if err != nil {
-			return false
+			return false, err
 		}
 
 		source := helpers.Find(*availableFolders, func(f api.ResponseFolder) bool {
 			return f.Name == folderName && f.UserId == userData.UserId
 		})
 		if source == nil {
-			helpers.PrintErr("Folder is not available or not exists")
-			return false
+			return false, helpers.NotFoundError("Folder is not available or not exists")
 		}
 		folderId = source.SherryId
 	} else {
@@ -332,132 +414,202 @@ func GetSharedFolder(user string, yes bool, localPath string, name string) bool
 
 	response, err := api.FolderGet(folderId, credentials.AccessToken)
 	if err != nil {
-		return false
+		return false, err
 	}
 
 	files, err := api.FolderFiles(folderId, credentials.AccessToken)
 	if err != nil {
-		return false
+		return false, err
 	}
 
-	helpers.PrintJson(response)
+	if !helpers.IsStructuredOutput() {
+		helpers.PrintJson(response)
+	}
 
 	conf := config.GetConfig()
 	sourceId := generateSourceId(credentials.UserId, response.SherryId)
+
+	watcherIndex := helpers.IndexOfFunc(conf.Watchers, func(w config.Watcher) bool {
+		return helpers.PreparePath(w.LocalPath) == localPath
+	})
+	if watcherIndex != -1 && conf.Watchers[watcherIndex].Source != sourceId {
+		return false, helpers.ConflictError("%s is already watched for another folder", localPath)
+	}
+
 	conf.Sources[sourceId] = responseToSource(response, credentials.UserId)
-	watcher := createWatcher(sourceId, credentials.UserId, response.SherryId, localPath, false)
-	conf.Watchers = append(conf.Watchers, watcher)
+	if watcherIndex == -1 {
+		conf.Watchers = append(conf.Watchers, createWatcher(sourceId, credentials.UserId, response.SherryId, localPath, false))
+		watcherIndex = len(conf.Watchers) - 1
+	}
 
 	helpers.PrintMessage(fmt.Sprintf("Creating directory at %s", localPath))
 	err = os.MkdirAll(localPath, os.ModePerm)
 	if err != nil {
-		helpers.PrintErr(err.Error())
-		return false
+		return true, helpers.FailureError(err.Error())
 	}
 
-	for _, file := range *files {
-		err = api.FolderFileDownload(folderId, file.Path, credentials.AccessToken, path.Join(localPath, file.Path))
-		if err != nil {
-			helpers.PrintErr(err.Error())
-			continue
-		}
+	watcher := conf.Watchers[watcherIndex]
+	index, err := config.ReadIndex(watcher.HashesId)
+	if err != nil {
+		return true, err
 	}
 
-	helpers.PrintMessage(fmt.Sprintf("Sherry watching at %s", localPath))
+	summary := downloadFiles(folderId, localPath, credentials.AccessToken, *files, options, index)
+	conf.Watchers[watcherIndex].Complete = len(summary.Failed) == 0
+	watcher = conf.Watchers[watcherIndex]
 
-	return true
-}
+	if err := config.CommitIndex(watcher.HashesId, index); err != nil {
+		return true, err
+	}
 
-func DisplaySharedFolder(user string, name string) bool {
-	name = helpers.Input("Folder name", name, helpers.IsWordValidator, "", false)
+	helpers.PrintResult(Result{Source: conf.Sources[sourceId], Watcher: &watcher, Download: &summary}, func() {
+		printDownloadSummary(summary)
+		helpers.PrintMessage(fmt.Sprintf("Sherry watching at %s", localPath))
+	})
 
-	credentials := auth.FindUserByUsername(user, true)
-	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
+	if len(summary.Failed) != 0 {
+		return true, helpers.NewError(
+			constants.ExitNetwork,
+			"%d of %d files failed to download, run the command again with --resume to retry them",
+			len(summary.Failed), summary.Files,
+		)
 	}
-	if credentials.Expired {
-		helpers.PrintErr("Your session has expired")
-		return false
+	return true, nil
+}
+
+func DisplaySharedFolder(user string, name string) (bool, error) {
+	name, err := helpers.Input("Folder name", "folder argument", name, helpers.IsWordValidator, "", false)
+	if err != nil {
+		return false, err
+	}
+
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
 	}
 
 	availableFolders, err := api.FolderGetAvailable(credentials.AccessToken)
 	if err != nil {
-		return false
+		return false, err
 	}
 
-	isEmpty := true
+	results := []ShowResult{}
 	for _, s := range *availableFolders {
 		if s.Name != name {
 			continue
 		}
-		isEmpty = false
 		source := responseToSource(&s, credentials.UserId)
 		owner, e := api.UserFindById(s.UserId, credentials.AccessToken)
 		if e != nil {
-			return false
-		}
-		helpers.PrintMessage(fmt.Sprintf("Folder: %s", source.Name))
-		helpers.PrintJson(source)
-		helpers.PrintMessage(fmt.Sprintf(
-			"Clone using: %s",
-			helpers.WithColor([]int{helpers.ConsoleFgDarkGreen, helpers.ConsoleUnderline}, fmt.Sprintf("shr folder get %s:%s", owner.Username, source.Name)),
-		))
-		helpers.PrintMessage(fmt.Sprintf(
-			"         or: %s",
-			helpers.WithColor([]int{helpers.ConsoleFgDarkGreen, helpers.ConsoleUnderline}, fmt.Sprintf("shr folder get %s", source.Id)),
-		))
-	}
-	if isEmpty {
-		helpers.PrintErr(fmt.Sprintf(
+			return false, e
+		}
+		results = append(results, ShowResult{
+			Source: source,
+			Owner:  owner.Username,
+			Clone: []string{
+				fmt.Sprintf("shr folder get %s:%s", owner.Username, source.Name),
+				fmt.Sprintf("shr folder get %s", source.Id),
+			},
+		})
+	}
+	if len(results) == 0 {
+		return false, helpers.NotFoundError(
 			"Folder %s is not available or not exists",
 			helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, name),
-		))
-	}
+		)
+	}
+
+	helpers.PrintResult(results, func() {
+		for _, r := range results {
+			helpers.PrintMessage(fmt.Sprintf("Folder: %s", r.Name))
+			helpers.PrintJson(r.Source)
+			helpers.PrintMessage(fmt.Sprintf(
+				"Clone using: %s",
+				helpers.WithColor([]int{helpers.ConsoleFgDarkGreen, helpers.ConsoleUnderline}, r.Clone[0]),
+			))
+			helpers.PrintMessage(fmt.Sprintf(
+				"         or: %s",
+				helpers.WithColor([]int{helpers.ConsoleFgDarkGreen, helpers.ConsoleUnderline}, r.Clone[1]),
+			))
+		}
+	})
 
-	return false
+	return false, nil
 }
 
-func UpdateSharedFolder(user string, name string, settings map[string]string) bool {
-	name = helpers.Input("Folder name", name, helpers.IsWordValidator, "", false)
-	credentials := auth.FindUserByUsername(user, true)
-	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
+func getUpdatePayload(source *api.ResponseFolder, settings map[string]string) (*api.PayloadFolder, error) {
+	settings = prepareSettings(settings)
+	payload := api.PayloadFolder{
+		Name: source.Name,
+	}
+	if name := settings["name"]; name != "" {
+		if helpers.IsWordValidator(name) != nil {
+			return nil, helpers.UsageError("Invalid Folder name: %s", name)
+		}
+		payload.Name = name
+	}
+	var err error
+	if payload.AllowDir, err = helpers.ParseBool("Allow directory", settings["allowDir"], source.AllowDir); err != nil {
+		return nil, err
+	}
+	if payload.MaxFileSize, err = helpers.ParseDataSize("Max file size", settings["maxFileSize"], source.MaxFileSize, constants.MaxFileSize); err != nil {
+		return nil, err
+	}
+	if payload.MaxDirSize, err = helpers.ParseDataSize("Max directory size", settings["maxDirSize"], source.MaxDirSize, constants.MaxDirSize); err != nil {
+		return nil, err
+	}
+	payload.AllowedFileNames, err = helpers.ParseValueArray(
+		"Allowed file names",
+		settings["allowedFileNames"],
+		helpers.IsGlobValidator, helpers.ToJoinedValues(helpers.Map(source.AllowedFileNames, func(f api.ResponseFolderAllowedFileNames) string {
+			return f.Name
+		})),
+	)
+	if err != nil {
+		return nil, err
 	}
-	if credentials.Expired {
-		helpers.PrintErr("Your session has expired")
-		return false
+	payload.AllowedFileTypes, err = helpers.ParseValueArray(
+		"Allowed file types",
+		settings["allowedFileTypes"],
+		helpers.IsMimeTypeValidator, helpers.ToJoinedValues(helpers.Map(source.AllowedFileTypes, func(f api.ResponseFolderAllowedFileTypes) string {
+			return f.Type
+		})),
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &payload, nil
+}
 
-	source := getAvailableSource(name, *credentials)
-	if source == nil {
-		helpers.PrintErr("Folder is not available or not exists")
-		return false
-	}
-
-	response, err := api.FolderUpdate(source.SherryId, api.PayloadFolder{
-		Name:        source.Name,
-		AllowDir:    helpers.ParseBool("Allow directory", settings["allowDir"], source.AllowDir),
-		MaxFileSize: helpers.ParseDataSize("Max file size", settings["maxFileSize"], source.MaxFileSize, constants.MaxFileSize),
-		MaxDirSize:  helpers.ParseDataSize("Max directory size", settings["maxDirSize"], source.MaxDirSize, constants.MaxDirSize),
-		AllowedFileNames: helpers.ParseValueArray(
-			"Allowed file names",
-			settings["allowedFileNames"],
-			helpers.IsGlobValidator, helpers.ToJoinedValues(helpers.Map(source.AllowedFileNames, func(f api.ResponseFolderAllowedFileNames) string {
-				return f.Name
-			})),
-		),
-		AllowedFileTypes: helpers.ParseValueArray(
-			"Allowed file types",
-			settings["allowedFileTypes"],
-			helpers.IsMimeTypeValidator, helpers.ToJoinedValues(helpers.Map(source.AllowedFileTypes, func(f api.ResponseFolderAllowedFileTypes) string {
-				return f.Type
-			})),
-		),
-	}, credentials.AccessToken)
+func UpdateSharedFolder(user string, name string, settings map[string]string) (bool, error) {
+	name, err := helpers.Input("Folder name", "folder argument", name, helpers.IsWordValidator, "", false)
 	if err != nil {
-		return false
+		return false, err
+	}
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	source, err := getAvailableSource(name, *credentials)
+	if err != nil {
+		return false, err
+	}
+
+	payload, err := getUpdatePayload(source, settings)
+	if err != nil {
+		return false, err
+	}
+	renamed := payload.Name != source.Name
+	if renamed {
+		if _, err := getAvailableSource(payload.Name, *credentials); err == nil {
+			return false, helpers.ConflictError("Folder %s already exists", payload.Name)
+		}
+	}
+
+	response, err := api.FolderUpdate(source.SherryId, *payload, credentials.AccessToken)
+	if err != nil {
+		return false, err
 	}
 
 	conf := config.GetConfig()
@@ -467,6 +619,7 @@ func UpdateSharedFolder(user string, name string, settings map[string]string) bo
 			continue
 		}
 
+		s.Name = estSource.Name
 		s.AllowDir = estSource.AllowDir
 		s.MaxFileSize = estSource.MaxFileSize
 		s.MaxDirSize = estSource.MaxDirSize
@@ -475,13 +628,25 @@ func UpdateSharedFolder(user string, name string, settings map[string]string) bo
 		conf.Sources[key] = s
 	}
 
-	helpers.PrintMessage(fmt.Sprintf("Folder was updated:"))
-	helpers.PrintJson(estSource)
+	if renamed {
+		helpers.PrintMessage(helpers.WithColor([]int{helpers.ConsoleFgDarkYellow}, fmt.Sprintf(
+			"Folder was renamed, collaborators have to use %s:%s instead of %s:%s to get it",
+			credentials.Username, estSource.Name, credentials.Username, source.Name,
+		)))
+	}
+	helpers.PrintResult(Result{Source: estSource}, func() {
+		helpers.PrintMessage(fmt.Sprintf("Folder was updated:"))
+		helpers.PrintJson(estSource)
+	})
 
-	return true
+	return true, nil
 }
 
-func UnwatchSharedFolder(path string, yes bool, force bool) bool {
+func RenameSharedFolder(user string, name string, newName string) (bool, error) {
+	return UpdateSharedFolder(user, name, map[string]string{"name": newName})
+}
+
+func UnwatchSharedFolder(path string, yes bool, force bool) (bool, error) {
 	path = helpers.PreparePath(path)
 
 	var watcher *config.Watcher
@@ -489,27 +654,25 @@ func UnwatchSharedFolder(path string, yes bool, force bool) bool {
 		wPath := helpers.PreparePath(w.LocalPath)
 		isChild, err := helpers.IsChildPath(path, wPath)
 		if err != nil {
-			helpers.PrintErr("Error while checking path")
-			return false
+			return false, helpers.FailureError("Error while checking path")
 		}
 		if isChild {
-			if wPath != path {
-				if yes || helpers.Confirmation("Looks like it is not th root of shared directory, unwatch anyway?", "", confirmation.No) {
-					watcher = &w
-				} else {
-					helpers.PrintErr("Aborting...")
-					return false
+			if wPath != path && !yes {
+				confirmed, err := helpers.Confirmation("Looks like it is not th root of shared directory, unwatch anyway?", "--yes", "", confirmation.No)
+				if err != nil {
+					return false, err
+				}
+				if !confirmed {
+					return false, helpers.FailureError("Aborting...")
 				}
-			} else {
-				watcher = &w
 			}
+			watcher = &w
 			break
 		}
 	}
 
 	if watcher == nil {
-		helpers.PrintErr("No watcher found")
-		return false
+		return false, helpers.NotFoundError("No watcher found")
 	}
 
 	conf := config.GetConfig()
@@ -522,53 +685,75 @@ func UnwatchSharedFolder(path string, yes bool, force bool) bool {
 	}
 	conf.Watchers = newWatchers
 
+	// The source is removed from the config when the folder is deleted, the result still describes it
+	source := conf.Sources[watcher.Source]
 	if force {
-		source := conf.Sources[watcher.Source]
 		if source.UserId != source.OwnerId {
-			if yes || helpers.Confirmation("You are not the owner of the folder and can't delete it, unwatch anyway?", "", confirmation.Undecided) {
-				return true
-			} else {
-				helpers.PrintErr("Aborting...")
-				return false
+			if yes {
+				return true, config.RemoveIndex(watcher.HashesId)
+			}
+			confirmed, err := helpers.Confirmation("You are not the owner of the folder and can't delete it, unwatch anyway?", "--yes", "", confirmation.Undecided)
+			if err != nil {
+				return false, err
+			}
+			if confirmed {
+				return true, config.RemoveIndex(watcher.HashesId)
 			}
+			return false, helpers.FailureError("Aborting...")
 		}
 
-		credentials := auth.GetUserById(watcher.UserId)
-		e := api.FolderDelete(conf.Sources[watcher.Source].Id, credentials.AccessToken)
+		credentials, err := auth.GetActiveUserById(watcher.UserId)
+		if err != nil {
+			return false, err
+		}
+		e := api.FolderDelete(source.Id, credentials.AccessToken)
 		if e != nil {
-			helpers.PrintErr("Failed to delete folder, aborting...")
-			return false
+			return false, helpers.WrapError(helpers.GetExitCode(e), e, fmt.Sprintf("Failed to delete folder, aborting...\n%s", e))
+		}
+		if _, err := removeFolderFromConfig(source.Id); err != nil {
+			return true, err
 		}
 	}
 
-	return true
+	if err := config.RemoveIndex(watcher.HashesId); err != nil {
+		return true, err
+	}
+
+	helpers.PrintResult(Result{Source: source, Watcher: watcher}, func() {
+		helpers.PrintMessage(fmt.Sprintf("Stopped watching %s", watcher.LocalPath))
+	})
+
+	return true, nil
 }
 
-func ListSharedFolders(user string, available bool) bool {
+func ListSharedFolders(user string, available bool) (bool, error) {
 	var users []config.Credentials
-	if user == "" {
-		for _, c := range config.GetAuthConfig().Sources {
-			users = append(users, c)
-		}
-	} else {
-		credentials := auth.FindUserByUsername(user, false)
+	if user != "" {
+		credentials := auth.FindUser(user)
 		if credentials == nil {
-			helpers.PrintErr("User not found")
-			return false
+			return false, helpers.NotFoundError("User not found")
 		}
 		users = append(users, *credentials)
+	} else {
+		credentials, context, err := auth.FindContextUser()
+		switch {
+		case err != nil && os.Getenv(constants.EnvUser) != "":
+			return false, err
+		case err == nil && context.Source != auth.ContextDefault:
+			users = append(users, *credentials)
+		default:
+			// Without a user selected for the session or by the working directory, folders of every user are listed
+			users = auth.GetSortedUsers()
+		}
 	}
 
+	results := []UserFoldersResult{}
 	for _, u := range users {
-		type Map struct {
-			watchers []config.Watcher
-			source   config.Source
-		}
 		var sources []config.Source
 		if available {
 			availableFolders, err := api.FolderGetAvailable(u.AccessToken)
 			if err != nil {
-				return false
+				return false, err
 			}
 			for _, s := range *availableFolders {
 				sources = append(sources, responseToSource(&s, u.UserId))
@@ -580,37 +765,53 @@ func ListSharedFolders(user string, available bool) bool {
 				}
 			}
 		}
-		helpers.PrintMessage(fmt.Sprintf("Folders for user: %s", auth.GetUserString(u)))
-		helpers.PrintMessage("")
+		sort.Slice(sources, func(i, j int) bool {
+			return sources[i].Name < sources[j].Name
+		})
+
+		folders := []SourceResult{}
 		for _, s := range sources {
-			helpers.PrintMessage(fmt.Sprintf("Source: %s", s.Name))
-			helpers.PrintJson(s)
 			sourceKey := generateSourceId(u.UserId, s.Id)
 			watchers := helpers.Filter(config.GetConfig().Watchers, func(watcher config.Watcher) bool {
 				return sourceKey == watcher.Source
 			})
-			if len(watchers) != 0 {
-				helpers.PrintMessage("Watching in these paths:")
-				for _, w := range watchers {
-					helpers.PrintMessage(fmt.Sprintf("  %s", w.LocalPath))
+			folders = append(folders, SourceResult{
+				Source: s,
+				Watchers: helpers.Map(watchers, func(w config.Watcher) string {
+					return w.LocalPath
+				}),
+			})
+		}
+		results = append(results, UserFoldersResult{User: auth.ToUserInfo(u), Folders: folders})
+	}
+
+	helpers.PrintResult(results, func() {
+		for _, r := range results {
+			helpers.PrintMessage(fmt.Sprintf("Folders for user: %s(%s)", r.User.Username, r.User.Email))
+			helpers.PrintMessage("")
+			for _, s := range r.Folders {
+				helpers.PrintMessage(fmt.Sprintf("Source: %s", s.Name))
+				helpers.PrintJson(s.Source)
+				if len(s.Watchers) != 0 {
+					helpers.PrintMessage("Watching in these paths:")
+					for _, w := range s.Watchers {
+						helpers.PrintMessage(fmt.Sprintf("  %s", w))
+					}
+				} else {
+					helpers.PrintMessage("No currently watching paths")
 				}
-			} else {
-				helpers.PrintMessage("No currently watching paths")
+				helpers.PrintMessage("")
 			}
 			helpers.PrintMessage("")
 		}
-		helpers.PrintMessage("")
-	}
+	})
 
-	return false
+	return false, nil
 }
 
 func getTargetUser(target string, accessToken string) (*api.ResponseUser, error) {
-	var err error
 	if target == "" {
-		err = errors.New("target user is required")
-		helpers.PrintErr(err.Error())
-		return nil, err
+		return nil, helpers.UsageError("Target user is required")
 	}
 
 	if helpers.IsWordValidator(target) == nil {
@@ -618,96 +819,146 @@ func getTargetUser(target string, accessToken string) (*api.ResponseUser, error)
 	} else if helpers.IsIdValidator(target) == nil {
 		return api.UserFindById(target, accessToken)
 	} else {
-		panic("Invalid target user")
+		return nil, helpers.UsageError("Invalid target user: %s", target)
 	}
 }
 
-func RevokePermission(user, target, name string) bool {
-	params := getFolderPermissionsParams(target, name, "", false)
+func revokePermission(source *api.ResponseFolder, targetUser *api.ResponseUser, credentials config.Credentials) error {
+	if err := api.FolderPermissionRevoke(source.SherryId, targetUser.UserId, credentials.AccessToken); err != nil {
+		return err
+	}
+	return forgetExpiry(source.SherryId, targetUser.UserId)
+}
 
-	credentials := auth.FindUserByUsername(user, true)
-	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
+func RevokePermission(user, target, name string) (bool, error) {
+	params, err := getFolderPermissionsParams(target, name, "", false)
+	if err != nil {
+		return false, err
 	}
-	if credentials.Expired {
-		helpers.PrintErr("Your session has expired")
-		return false
+
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
 	}
 
-	targetUser, e := getTargetUser(params.Target, credentials.AccessToken)
-	if e != nil {
-		return false
+	source, err := getAvailableSource(params.Name, *credentials)
+	if err != nil {
+		return false, err
 	}
 
-	if targetUser.UserId == credentials.UserId {
-		helpers.PrintErr("You can't revoke permission to yourself")
-		return false
+	result := PermissionResult{Folder: source.Name, Action: api.PermissionActionRefuse}
+	if group.IsGroup(params.Target) {
+		return applyToGroup(params.Target, *credentials, result, func(targetUser *api.ResponseUser) error {
+			return revokePermission(source, targetUser, *credentials)
+		})
 	}
 
-	source := getAvailableSource(params.Name, *credentials)
-	if source == nil {
-		helpers.PrintErr("Folder is not available or not exists")
-		return false
+	targetUser, err := getTargetUser(params.Target, credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	if targetUser.UserId == credentials.UserId {
+		return false, helpers.UsageError("You can't revoke permission to yourself")
 	}
 
-	if api.FolderPermission(source.SherryId, targetUser.UserId, api.PayloadFolderPermission{
-		Action: api.PermissionActionRefuse,
-		Role:   api.PermissionRoleOwner, // Required by api, but will be ignored
-	}, credentials.AccessToken) != nil {
-		return false
+	if err := revokePermission(source, targetUser, *credentials); err != nil {
+		return false, err
 	}
 
-	helpers.PrintMessage(fmt.Sprintf("Permission revoked from %s", auth.GetUserString(config.Credentials{
-		UserId:   targetUser.UserId,
-		Username: targetUser.Username,
-		Email:    targetUser.Email,
-	})))
+	result.UserId, result.Username, result.Email = targetUser.UserId, targetUser.Username, targetUser.Email
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("Permission revoked from %s", auth.GetUserString(config.Credentials{
+			UserId:   targetUser.UserId,
+			Username: targetUser.Username,
+			Email:    targetUser.Email,
+		})))
+	})
 
-	return false
+	return false, nil
 }
 
-func GrantPermission(user, target, name, role string) bool {
-	params := getFolderPermissionsParams(target, name, role, true)
+func grantPermission(source *api.ResponseFolder, targetUser *api.ResponseUser, role string, expiresAt *time.Time, credentials config.Credentials) error {
+	err := api.FolderPermission(source.SherryId, targetUser.UserId, api.PayloadFolderPermission{
+		Role:   role,
+		Action: api.PermissionActionGrant,
+	}, credentials.AccessToken)
+	if err != nil {
+		return err
+	}
+	return config.UpdateExpiryLedger(func(ledger *config.ExpiryLedger) {
+		if expiresAt == nil {
+			ledger.Remove(source.SherryId, targetUser.UserId)
+			return
+		}
+		ledger.Set(config.ExpiringGrant{
+			FolderId:  source.SherryId,
+			Folder:    source.Name,
+			UserId:    targetUser.UserId,
+			Username:  targetUser.Username,
+			Role:      role,
+			GrantedBy: credentials.UserId,
+			ExpiresAt: *expiresAt,
+		})
+	})
+}
 
-	credentials := auth.FindUserByUsername(user, true)
-	if credentials == nil {
-		helpers.PrintErr("User not found")
-		return false
+func GrantPermission(user, target, name, role, expires string) (bool, error) {
+	params, err := getFolderPermissionsParams(target, name, role, true)
+	if err != nil {
+		return false, err
 	}
-	if credentials.Expired {
-		helpers.PrintErr("Your session has expired")
-		return false
+	var expiresAt *time.Time
+	if expires != "" {
+		duration, err := helpers.ParseDuration("expiry", expires)
+		if err != nil {
+			return false, err
+		}
+		t := time.Now().Add(duration).Truncate(time.Second)
+		expiresAt = &t
 	}
 
-	targetUser, e := getTargetUser(params.Target, credentials.AccessToken)
-	if e != nil {
-		return false
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
 	}
 
-	if targetUser.UserId == credentials.UserId {
-		helpers.PrintErr("You can't grant permission to yourself")
-		return false
+	source, err := getAvailableSource(params.Name, *credentials)
+	if err != nil {
+		return false, err
 	}
 
-	source := getAvailableSource(params.Name, *credentials)
-	if source == nil {
-		helpers.PrintErr("Folder is not available or not exists")
-		return false
+	result := PermissionResult{Folder: source.Name, Role: params.Role, Action: api.PermissionActionGrant, ExpiresAt: expiresAt}
+	if group.IsGroup(params.Target) {
+		return applyToGroup(params.Target, *credentials, result, func(targetUser *api.ResponseUser) error {
+			return grantPermission(source, targetUser, params.Role, expiresAt, *credentials)
+		})
 	}
 
-	if api.FolderPermission(source.SherryId, targetUser.UserId, api.PayloadFolderPermission{
-		Role:   params.Role,
-		Action: api.PermissionActionGrant,
-	}, credentials.AccessToken) != nil {
-		return false
+	targetUser, err := getTargetUser(params.Target, credentials.AccessToken)
+	if err != nil {
+		return false, err
 	}
 
-	helpers.PrintMessage(fmt.Sprintf("Permission granted to %s: %s", auth.GetUserString(config.Credentials{
-		UserId:   targetUser.UserId,
-		Username: targetUser.Username,
-		Email:    targetUser.Email,
-	}), params.Role))
+	if targetUser.UserId == credentials.UserId {
+		return false, helpers.UsageError("You can't grant permission to yourself")
+	}
+
+	if err := grantPermission(source, targetUser, params.Role, expiresAt, *credentials); err != nil {
+		return false, err
+	}
+
+	result.UserId, result.Username, result.Email = targetUser.UserId, targetUser.Username, targetUser.Email
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("Permission granted to %s: %s", auth.GetUserString(config.Credentials{
+			UserId:   targetUser.UserId,
+			Username: targetUser.Username,
+			Email:    targetUser.Email,
+		}), params.Role))
+		if expiresAt != nil {
+			helpers.PrintMessage(fmt.Sprintf("Expires %s, run `shr folder permission sweep` to revoke expired permissions", expiresAt.Format(time.DateTime)))
+		}
+	})
 
-	return false
+	return false, nil
 }
@@ -0,0 +1,26 @@
+package folder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sherry/shr/api"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"testing"
+)
+
+func TestGetUpdatePayload(t *testing.T) {
+	source := &api.ResponseFolder{Name: "docs", AllowDir: true, MaxFileSize: 1024}
+
+	payload, err := getUpdatePayload(source, map[string]string{"Name": "notes", "allow-dir": "false"})
+	assert.NoError(t, err)
+	assert.Equal(t, "notes", payload.Name)
+	assert.False(t, payload.AllowDir)
+	assert.Equal(t, uint64(1024), payload.MaxFileSize)
+
+	payload, err = getUpdatePayload(source, map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "docs", payload.Name)
+
+	_, err = getUpdatePayload(source, map[string]string{"NAME": "bad name"})
+	assert.Equal(t, constants.ExitUsage, helpers.GetExitCode(err))
+}
@@ -0,0 +1,77 @@
+package folder
+
+import (
+	"fmt"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"sherry/shr/group"
+	"sherry/shr/helpers"
+)
+
+func printGroupResults(results []PermissionResult) {
+	nameWidth, emailWidth := 0, 0
+	for _, r := range results {
+		nameWidth = max(nameWidth, len(r.Username))
+		emailWidth = max(emailWidth, len(r.Email))
+	}
+	failed := 0
+	for _, r := range results {
+		line := fmt.Sprintf("  %-*s  %-*s  ", nameWidth, r.Username, emailWidth, r.Email)
+		switch {
+		case r.Error != "":
+			failed++
+			line = helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, line+r.Error)
+		case r.Action == api.PermissionActionGrant:
+			line += fmt.Sprintf("granted %s", r.Role)
+		default:
+			line += "revoked"
+		}
+		helpers.PrintMessage(line)
+	}
+	helpers.PrintMessage(fmt.Sprintf("%d of %d members updated", len(results)-failed, len(results)))
+}
+
+// applyToGroup resolves every member of the group and changes its permission with fn,
+// a failed member doesn't stop the others, the own user is skipped
+func applyToGroup(target string, credentials config.Credentials, result PermissionResult, fn func(*api.ResponseUser) error) (bool, error) {
+	members, err := group.GetMembers(target)
+	if err != nil {
+		return false, err
+	}
+
+	results := []PermissionResult{}
+	var firstErr error
+	for _, member := range members {
+		r := result
+		r.Username = member
+		targetUser, err := getTargetUser(member, credentials.AccessToken)
+		if err == nil {
+			if targetUser.UserId == credentials.UserId {
+				helpers.PrintMessage(fmt.Sprintf("Skipping %s, you can't change your own permission", member))
+				continue
+			}
+			r.UserId, r.Username, r.Email = targetUser.UserId, targetUser.Username, targetUser.Email
+			err = fn(targetUser)
+		}
+		if err != nil {
+			r.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		results = append(results, r)
+	}
+
+	helpers.PrintResult(results, func() {
+		helpers.PrintMessage(fmt.Sprintf("Folder %s, group %s:", result.Folder, target))
+		printGroupResults(results)
+	})
+
+	if firstErr != nil {
+		failed := len(helpers.Filter(results, func(r PermissionResult) bool {
+			return r.Error != ""
+		}))
+		return false, helpers.WrapError(helpers.GetExitCode(firstErr), firstErr, fmt.Sprintf("%d of %d members failed", failed, len(results)))
+	}
+	return false, nil
+}
@@ -0,0 +1,156 @@
+package folder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"sherry/shr/ignore"
+	"strings"
+)
+
+type IgnoreResult = struct {
+	Folder    string   `json:"folder"`
+	LocalPath string   `json:"localPath"`
+	File      []string `json:"file"`
+	Local     []string `json:"local"`
+}
+
+func getPatterns(lines []string) []string {
+	patterns := []string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+func writeIgnoreFile(file string, lines []string) error {
+	data := strings.Join(lines, "\n")
+	if data != "" {
+		data += "\n"
+	}
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		return helpers.FailureError("Can't write %s: %s", file, err)
+	}
+	return nil
+}
+
+// findWatcherIndex returns the position of the watcher containing localPath in the configuration
+func findWatcherIndex(localPath string) (int, error) {
+	watcher, _, err := findWatchedFolder(localPath)
+	if err != nil {
+		return -1, err
+	}
+	return helpers.IndexOfFunc(config.GetConfig().Watchers, func(w config.Watcher) bool {
+		return w.LocalPath == watcher.LocalPath
+	}), nil
+}
+
+func printIgnoreResult(localPath string) error {
+	watcher, source, err := findWatchedFolder(localPath)
+	if err != nil {
+		return err
+	}
+	root := helpers.PreparePath(watcher.LocalPath)
+	lines, err := ignore.ReadFile(filepath.Join(root, ignore.FileName))
+	if err != nil {
+		return helpers.FailureError("Can't read %s: %s", ignore.FileName, err)
+	}
+
+	result := IgnoreResult{
+		Folder:    source.Name,
+		LocalPath: root,
+		File:      getPatterns(lines),
+		Local:     helpers.EmptyIfNull(watcher.Ignore),
+	}
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("%s (shared with the folder):", ignore.FileName))
+		for _, p := range result.File {
+			helpers.PrintMessage(fmt.Sprintf("  %s", p))
+		}
+		helpers.PrintMessage("Local (this machine only):")
+		for _, p := range result.Local {
+			helpers.PrintMessage(fmt.Sprintf("  %s", p))
+		}
+	})
+	return nil
+}
+
+func ListIgnorePatterns(localPath string) (bool, error) {
+	return false, printIgnoreResult(localPath)
+}
+
+func AddIgnorePatterns(localPath string, patterns []string, local bool) (bool, error) {
+	i, err := findWatcherIndex(localPath)
+	if err != nil {
+		return false, err
+	}
+	watcher := &config.GetConfig().Watchers[i]
+
+	if local {
+		for _, p := range patterns {
+			if !helpers.Includes(watcher.Ignore, p) {
+				watcher.Ignore = append(watcher.Ignore, p)
+			}
+		}
+		return true, printIgnoreResult(localPath)
+	}
+
+	file := filepath.Join(helpers.PreparePath(watcher.LocalPath), ignore.FileName)
+	lines, err := ignore.ReadFile(file)
+	if err != nil {
+		return false, helpers.FailureError("Can't read %s: %s", file, err)
+	}
+	for _, p := range patterns {
+		if !helpers.Includes(getPatterns(lines), p) {
+			lines = append(lines, p)
+		}
+	}
+	if err := writeIgnoreFile(file, lines); err != nil {
+		return false, err
+	}
+	return false, printIgnoreResult(localPath)
+}
+
+func RemoveIgnorePatterns(localPath string, patterns []string, local bool) (bool, error) {
+	i, err := findWatcherIndex(localPath)
+	if err != nil {
+		return false, err
+	}
+	watcher := &config.GetConfig().Watchers[i]
+
+	removed := 0
+	keep := func(line string) bool {
+		if helpers.Includes(patterns, strings.TrimSpace(line)) {
+			removed++
+			return false
+		}
+		return true
+	}
+
+	if local {
+		watcher.Ignore = helpers.Filter(watcher.Ignore, keep)
+		if removed == 0 {
+			return false, helpers.NotFoundError("No matching local patterns found")
+		}
+		return true, printIgnoreResult(localPath)
+	}
+
+	file := filepath.Join(helpers.PreparePath(watcher.LocalPath), ignore.FileName)
+	lines, err := ignore.ReadFile(file)
+	if err != nil {
+		return false, helpers.FailureError("Can't read %s: %s", file, err)
+	}
+	lines = helpers.Filter(lines, keep)
+	if removed == 0 {
+		return false, helpers.NotFoundError("No matching patterns found in %s", file)
+	}
+	if err := writeIgnoreFile(file, lines); err != nil {
+		return false, err
+	}
+	return false, printIgnoreResult(localPath)
+}
@@ -0,0 +1,139 @@
+package folder
+
+import (
+	"fmt"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PermissionEntry = struct {
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	// ExpiresAt is set for permissions granted with expiry from this machine
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type PermissionListResult = struct {
+	Folder      string            `json:"folder"`
+	Id          string            `json:"id"`
+	Permissions []PermissionEntry `json:"permissions"`
+}
+
+var roleOrder = []string{api.PermissionRoleOwner, api.PermissionRoleWrite, api.PermissionRoleRead}
+
+// findFolder resolves a folder by id, owner_username:folder_name or name of a folder owned by the user
+func findFolder(folder string, credentials config.Credentials) (*api.ResponseFolder, error) {
+	if helpers.IsIdValidator(folder) == nil {
+		return api.FolderGet(folder, credentials.AccessToken)
+	}
+	if helpers.IsUsernameFolder(folder) != nil {
+		return getAvailableSource(folder, credentials)
+	}
+
+	args := strings.Split(folder, ":")
+	owner, err := api.UserFindByUsername(args[0], credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	availableFolders, err := api.FolderGetAvailable(credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	source := helpers.Find(*availableFolders, func(f api.ResponseFolder) bool {
+		return f.Name == args[1] && f.UserId == owner.UserId
+	})
+	if source == nil {
+		return nil, helpers.NotFoundError("Folder is not available or not exists")
+	}
+	return source, nil
+}
+
+func sortPermissions(permissions []PermissionEntry) {
+	sort.Slice(permissions, func(i, j int) bool {
+		a, b := helpers.IndexOf(roleOrder, permissions[i].Role), helpers.IndexOf(roleOrder, permissions[j].Role)
+		if a != b {
+			return a < b
+		}
+		return permissions[i].Username < permissions[j].Username
+	})
+}
+
+// getPermissions resolves users of the folder permissions, deleted users are listed by id only
+func getPermissions(folder *api.ResponseFolder, accessToken string) ([]PermissionEntry, error) {
+	ledger, err := config.ReadExpiryLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []PermissionEntry{}
+	for _, p := range folder.SherryPermission {
+		entry := PermissionEntry{UserId: p.UserId, Role: p.Role}
+		if grant := ledger.Find(folder.SherryId, p.UserId); grant != nil {
+			entry.ExpiresAt = &grant.ExpiresAt
+		}
+		user, err := api.UserFindById(p.UserId, accessToken)
+		if err != nil && helpers.GetExitCode(err) != constants.ExitNotFound {
+			return nil, err
+		}
+		if user != nil {
+			entry.Username = user.Username
+			entry.Email = user.Email
+		}
+		permissions = append(permissions, entry)
+	}
+	sortPermissions(permissions)
+	return permissions, nil
+}
+
+func printPermissions(result PermissionListResult) {
+	helpers.PrintMessage(fmt.Sprintf("Access to folder %s:", result.Folder))
+	names := helpers.Map(result.Permissions, func(p PermissionEntry) string {
+		if p.Username == "" {
+			return p.UserId
+		}
+		return p.Username
+	})
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	for i, p := range result.Permissions {
+		line := fmt.Sprintf("  %-5s  %-*s  %s", p.Role, width, names[i], p.Email)
+		if p.ExpiresAt != nil {
+			line += fmt.Sprintf(" (%s)", formatExpiry(*p.ExpiresAt))
+		}
+		helpers.PrintMessage(line)
+	}
+}
+
+func ListPermissions(user string, folder string) (bool, error) {
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := findFolder(folder, *credentials)
+	if err != nil {
+		return false, err
+	}
+
+	permissions, err := getPermissions(response, credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	result := PermissionListResult{Folder: response.Name, Id: response.SherryId, Permissions: permissions}
+	helpers.PrintResult(result, func() {
+		printPermissions(result)
+	})
+
+	return false, nil
+}
@@ -0,0 +1,127 @@
+package folder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"testing"
+	"time"
+)
+
+func TestSortPermissions(t *testing.T) {
+	permissions := []PermissionEntry{
+		{Username: "dave", Role: api.PermissionRoleRead},
+		{Username: "carol", Role: api.PermissionRoleWrite},
+		{Username: "bob", Role: api.PermissionRoleRead},
+		{Username: "alice", Role: api.PermissionRoleOwner},
+	}
+	sortPermissions(permissions)
+
+	assert.Equal(t, []string{"alice", "carol", "bob", "dave"}, usernames(permissions))
+}
+
+func usernames(permissions []PermissionEntry) []string {
+	var names []string
+	for _, p := range permissions {
+		names = append(names, p.Username)
+	}
+	return names
+}
+
+func TestPlanPermissions(t *testing.T) {
+	current := []PermissionEntry{
+		{UserId: "u-1", Username: "alice", Role: api.PermissionRoleOwner},
+		{UserId: "u-2", Username: "bob", Role: api.PermissionRoleRead},
+		{UserId: "u-3", Username: "carol", Role: api.PermissionRoleWrite},
+	}
+	desired := []PermissionEntry{
+		{UserId: "u-2", Username: "bob", Role: api.PermissionRoleWrite},
+		{UserId: "u-4", Username: "dave", Role: api.PermissionRoleRead},
+	}
+
+	t.Run("grant", func(t *testing.T) {
+		changes := planPermissions("docs", current, desired, false)
+		assert.Equal(t, []PermissionChange{
+			{Folder: "docs", UserId: "u-2", Username: "bob", Action: api.PermissionActionGrant, Role: api.PermissionRoleWrite, OldRole: api.PermissionRoleRead},
+			{Folder: "docs", UserId: "u-4", Username: "dave", Action: api.PermissionActionGrant, Role: api.PermissionRoleRead},
+		}, changes)
+	})
+
+	t.Run("prune keeps owners", func(t *testing.T) {
+		changes := planPermissions("docs", current, desired, true)
+		assert.Len(t, changes, 3)
+		assert.Equal(t, PermissionChange{Folder: "docs", UserId: "u-3", Username: "carol", Action: api.PermissionActionRefuse, OldRole: api.PermissionRoleWrite}, changes[2])
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		assert.Empty(t, planPermissions("docs", current, current[1:], true))
+	})
+}
+
+func TestExpiredGrants(t *testing.T) {
+	now := time.Now()
+	ledger := &config.ExpiryLedger{Grants: []config.ExpiringGrant{
+		{FolderId: "s-1", UserId: "u-2", GrantedBy: "u-1", ExpiresAt: now.Add(time.Hour)},
+		{FolderId: "s-1", UserId: "u-3", GrantedBy: "u-1", ExpiresAt: now.Add(-time.Minute)},
+		{FolderId: "s-2", UserId: "u-3", GrantedBy: "u-4", ExpiresAt: now.Add(-time.Hour)},
+		{FolderId: "s-2", UserId: "u-5", GrantedBy: "u-1", ExpiresAt: now},
+	}}
+
+	userIds := func(grants []config.ExpiringGrant) []string {
+		var ids []string
+		for _, g := range grants {
+			ids = append(ids, g.FolderId+"/"+g.UserId)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"s-2/u-3", "s-1/u-3", "s-2/u-5"}, userIds(expiredGrants(ledger, now, "")))
+	assert.Equal(t, []string{"s-1/u-3", "s-2/u-5"}, userIds(expiredGrants(ledger, now, "u-1")))
+}
+
+func TestResolveRequestExpiry(t *testing.T) {
+	grant := config.ExpiringGrant{FolderId: "s-1", UserId: "u-2", Role: api.PermissionRoleWrite, GrantedBy: "u-1", ExpiresAt: time.Now().Add(time.Hour)}
+	request := AccessRequestEntry{Id: "r-1", FolderId: "s-1", UserId: "u-2", Role: api.PermissionRoleWrite}
+
+	t.Run("deny keeps the expiring grant", func(t *testing.T) {
+		ledger := &config.ExpiryLedger{Grants: []config.ExpiringGrant{grant}}
+		resolveRequestExpiry(ledger, request, false)
+		assert.Equal(t, []config.ExpiringGrant{grant}, ledger.Grants)
+	})
+
+	t.Run("approve replaces the expiring grant", func(t *testing.T) {
+		ledger := &config.ExpiryLedger{Grants: []config.ExpiringGrant{grant}}
+		resolveRequestExpiry(ledger, request, true)
+		assert.Empty(t, ledger.Grants)
+	})
+}
+
+func TestTransferExpiringGrants(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	grants := func() *config.ExpiryLedger {
+		return &config.ExpiryLedger{Grants: []config.ExpiringGrant{
+			{FolderId: "s-1", UserId: "u-2", GrantedBy: "u-1", ExpiresAt: expiresAt},
+			{FolderId: "s-1", UserId: "u-3", GrantedBy: "u-1", ExpiresAt: expiresAt},
+			{FolderId: "s-2", UserId: "u-3", GrantedBy: "u-1", ExpiresAt: expiresAt},
+		}}
+	}
+
+	t.Run("reassign", func(t *testing.T) {
+		ledger := grants()
+		reassigned, dropped := transferExpiringGrants(ledger, "s-1", "u-1", "u-2", true)
+		assert.Equal(t, 1, reassigned)
+		assert.Equal(t, 0, dropped)
+		assert.Nil(t, ledger.Find("s-1", "u-2"))
+		assert.Equal(t, "u-2", ledger.Find("s-1", "u-3").GrantedBy)
+		assert.Equal(t, "u-1", ledger.Find("s-2", "u-3").GrantedBy)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		ledger := grants()
+		reassigned, dropped := transferExpiringGrants(ledger, "s-1", "u-1", "u-4", false)
+		assert.Equal(t, 0, reassigned)
+		assert.Equal(t, 2, dropped)
+		assert.Len(t, ledger.Grants, 1)
+		assert.NotNil(t, ledger.Find("s-2", "u-3"))
+	})
+}
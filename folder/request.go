@@ -0,0 +1,213 @@
+package folder
+
+import (
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sort"
+	"strings"
+)
+
+type AccessRequestEntry = struct {
+	Id       string `json:"id"`
+	FolderId string `json:"folderId"`
+	Folder   string `json:"folder"`
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+func getRole(role string) (string, error) {
+	role, err := helpers.Select("Role", "--role", strcase.ToCamel(role), []string{"Read", "Write"})
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(role), nil
+}
+
+func RequestAccess(user string, folder string, role string) (bool, error) {
+	folder, err := helpers.Input("Folder name in format owner_username:folder_name", "folder argument", folder, helpers.IsUsernameFolder, "", false)
+	if err != nil {
+		return false, err
+	}
+	if role, err = getRole(role); err != nil {
+		return false, err
+	}
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	args := strings.Split(folder, ":")
+	owner, err := api.UserFindByUsername(args[0], credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+	if owner.UserId == credentials.UserId {
+		return false, helpers.UsageError("You are the owner of %s", folder)
+	}
+	if current, err := findFolder(folder, *credentials); err == nil {
+		if helpers.Find(current.SherryPermission, func(p api.SherryPermission) bool {
+			return p.UserId == credentials.UserId && p.Role == role
+		}) != nil {
+			return false, helpers.ConflictError("You already have %s access to %s", role, folder)
+		}
+	}
+
+	response, err := api.AccessRequestCreate(api.PayloadAccessRequest{
+		OwnerId: owner.UserId,
+		Name:    args[1],
+		Role:    role,
+	}, credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	result := AccessRequestEntry{
+		Id:       response.AccessRequestId,
+		FolderId: response.SherryId,
+		Folder:   args[1],
+		UserId:   credentials.UserId,
+		Username: credentials.Username,
+		Email:    credentials.Email,
+		Role:     response.Role,
+	}
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("Requested %s access to %s, %s can approve it with:", result.Role, folder, owner.Username))
+		helpers.PrintMessage(fmt.Sprintf("  shr folder permission approve %s", result.Id))
+	})
+
+	return false, nil
+}
+
+// getPendingRequests resolves folder names and requesters of the pending requests
+func getPendingRequests(credentials config.Credentials) ([]AccessRequestEntry, error) {
+	pending, err := api.AccessRequestGetPending(credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	availableFolders, err := api.FolderGetAvailable(credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []AccessRequestEntry{}
+	for _, r := range *pending {
+		entry := AccessRequestEntry{Id: r.AccessRequestId, FolderId: r.SherryId, UserId: r.UserId, Role: r.Role}
+		if f := helpers.Find(*availableFolders, func(f api.ResponseFolder) bool {
+			return f.SherryId == r.SherryId
+		}); f != nil {
+			entry.Folder = f.Name
+		}
+		requester, err := api.UserFindById(r.UserId, credentials.AccessToken)
+		if err != nil && helpers.GetExitCode(err) != constants.ExitNotFound {
+			return nil, err
+		}
+		if requester != nil {
+			entry.Username = requester.Username
+			entry.Email = requester.Email
+		}
+		requests = append(requests, entry)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].Folder != requests[j].Folder {
+			return requests[i].Folder < requests[j].Folder
+		}
+		return requests[i].Username < requests[j].Username
+	})
+	return requests, nil
+}
+
+func ListPendingRequests(user string, name string) (bool, error) {
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	requests, err := getPendingRequests(*credentials)
+	if err != nil {
+		return false, err
+	}
+	if name != "" {
+		requests = helpers.EmptyIfNull(helpers.Filter(requests, func(r AccessRequestEntry) bool {
+			return r.Folder == name
+		}))
+	}
+
+	helpers.PrintResult(requests, func() {
+		if len(requests) == 0 {
+			helpers.PrintMessage("No pending access requests")
+			return
+		}
+		helpers.PrintMessage("Pending access requests:")
+		for _, r := range requests {
+			helpers.PrintMessage(fmt.Sprintf("  %s  %s  %s(%s)  %s", r.Id, r.Folder, r.Username, r.Email, r.Role))
+		}
+	})
+
+	return false, nil
+}
+
+// ResolveAccessRequest approves the request with the requested or the given role, or denies it
+func ResolveAccessRequest(user string, id string, approve bool, role string) (bool, error) {
+	id, err := helpers.Input("Request id", "request id argument", id, helpers.IsIdValidator, "", false)
+	if err != nil {
+		return false, err
+	}
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	requests, err := getPendingRequests(*credentials)
+	if err != nil {
+		return false, err
+	}
+	request := helpers.Find(requests, func(r AccessRequestEntry) bool {
+		return r.Id == id
+	})
+	if request == nil {
+		return false, helpers.NotFoundError("No pending access request %s", id)
+	}
+
+	payload := api.PayloadFolderPermission{Role: request.Role, Action: api.PermissionActionGrant}
+	if !approve {
+		payload.Action = api.PermissionActionRefuse
+	} else if role != "" {
+		if payload.Role, err = getRole(role); err != nil {
+			return false, err
+		}
+	}
+	if err := api.AccessRequestResolve(id, payload, credentials.AccessToken); err != nil {
+		return false, err
+	}
+	err = config.UpdateExpiryLedger(func(ledger *config.ExpiryLedger) {
+		resolveRequestExpiry(ledger, *request, approve)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	result := PermissionResult{
+		Folder:   request.Folder,
+		UserId:   request.UserId,
+		Username: request.Username,
+		Email:    request.Email,
+		Role:     helpers.If(approve, func() string { return payload.Role }, func() string { return "" }),
+		Action:   payload.Action,
+	}
+	helpers.PrintResult(result, func() {
+		if approve {
+			helpers.PrintMessage(fmt.Sprintf("Granted %s access to %s for %s", result.Role, result.Folder, result.Username))
+		} else {
+			helpers.PrintMessage(fmt.Sprintf("Denied access to %s for %s", result.Folder, result.Username))
+		}
+	})
+
+	return false, nil
+}
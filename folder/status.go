@@ -0,0 +1,96 @@
+package folder
+
+import (
+	"fmt"
+	"sherry/shr/helpers"
+)
+
+type StatusEntry = struct {
+	Path     string     `json:"path"`
+	Local    ChangeKind `json:"local,omitempty"`
+	Remote   ChangeKind `json:"remote,omitempty"`
+	Conflict bool       `json:"conflict"`
+}
+
+type StatusResult = struct {
+	Folder    string        `json:"folder"`
+	LocalPath string        `json:"localPath"`
+	Clean     bool          `json:"clean"`
+	Changes   []StatusEntry `json:"changes"`
+}
+
+func printStatusGroup(title string, color int, entries []StatusEntry, label func(StatusEntry) string) {
+	if len(entries) == 0 {
+		return
+	}
+	width := 0
+	for _, e := range entries {
+		width = max(width, len(label(e))+1)
+	}
+	helpers.PrintMessage(fmt.Sprintf("%s:", title))
+	for _, e := range entries {
+		helpers.PrintMessage(helpers.WithColor([]int{color}, fmt.Sprintf("  %-*s %s", width, label(e)+":", e.Path)))
+	}
+}
+
+func printStatusResult(result StatusResult) {
+	helpers.PrintMessage(fmt.Sprintf("Folder %s at %s", result.Folder, result.LocalPath))
+	if result.Clean {
+		helpers.PrintMessage("Nothing to synchronize, both sides are up to date")
+		return
+	}
+
+	var local, remote, conflicts []StatusEntry
+	for _, e := range result.Changes {
+		switch {
+		case e.Conflict:
+			conflicts = append(conflicts, e)
+		case e.Local != ChangeNone:
+			local = append(local, e)
+		default:
+			remote = append(remote, e)
+		}
+	}
+
+	helpers.PrintMessage("")
+	printStatusGroup("Local changes", helpers.ConsoleFgDarkGreen, local, func(e StatusEntry) string {
+		return e.Local
+	})
+	printStatusGroup("Changes on server", helpers.ConsoleFgDarkCyan, remote, func(e StatusEntry) string {
+		return e.Remote
+	})
+	printStatusGroup("Conflicts", helpers.ConsoleFgDarkRed, conflicts, func(e StatusEntry) string {
+		if e.Local == e.Remote {
+			return fmt.Sprintf("both %s", e.Local)
+		}
+		return fmt.Sprintf("%s locally, %s on server", e.Local, e.Remote)
+	})
+}
+
+func StatusSharedFolder(localPath string) (bool, error) {
+	state, err := loadFolderState(localPath)
+	if err != nil {
+		return false, err
+	}
+
+	result := StatusResult{
+		Folder:    state.Source.Name,
+		LocalPath: state.Root,
+		Changes:   []StatusEntry{},
+	}
+	for _, c := range diffTrees(state.Local, state.Remote, state.Index) {
+		result.Changes = append(result.Changes, StatusEntry{
+			Path:     c.Path,
+			Local:    c.LocalChange,
+			Remote:   c.RemoteChange,
+			Conflict: c.IsConflict(),
+		})
+	}
+	result.Clean = len(result.Changes) == 0
+
+	helpers.PrintResult(result, func() {
+		printStatusResult(result)
+	})
+
+	return false, nil
+}
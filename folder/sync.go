@@ -0,0 +1,396 @@
+package folder
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+	"path"
+	"path/filepath"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"sherry/shr/policy"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SyncAction = string
+
+const (
+	SyncMkdir        SyncAction = "mkdir"
+	SyncMkdirRemote  SyncAction = "mkdir-remote"
+	SyncDownload     SyncAction = "download"
+	SyncUpload       SyncAction = "upload"
+	SyncDeleteLocal  SyncAction = "delete-local"
+	SyncDeleteRemote SyncAction = "delete-remote"
+)
+
+var syncActionOrder = []SyncAction{SyncMkdir, SyncMkdirRemote, SyncDownload, SyncUpload, SyncDeleteLocal, SyncDeleteRemote}
+
+const (
+	PreferLocal  = "local"
+	PreferRemote = "remote"
+)
+
+type SyncStep = struct {
+	Action SyncAction `json:"action"`
+	Path   string     `json:"path"`
+	Size   uint64     `json:"size"`
+	Hash   string     `json:"hash,omitempty"`
+	Reason string     `json:"reason"`
+	// ConflictCopy keeps the losing version of a conflict: the local file is renamed to it before a download,
+	// the server file is downloaded to it before an upload
+	ConflictCopy string `json:"conflictCopy,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Remote is the server entry of downloaded files
+	Remote *api.FileResponse `json:"-"`
+}
+
+type SyncResult = struct {
+	Folder    string     `json:"folder"`
+	LocalPath string     `json:"localPath"`
+	DryRun    bool       `json:"dryRun"`
+	Steps     []SyncStep `json:"steps"`
+	Failed    int        `json:"failed"`
+}
+
+func uploadStep(f localFile, reason string) SyncStep {
+	return SyncStep{Action: SyncUpload, Path: f.Path, Size: f.Size, Hash: f.Hash, Reason: reason}
+}
+
+func downloadStep(f api.FileResponse, reason string) SyncStep {
+	return SyncStep{Action: SyncDownload, Path: helpers.NormalizePath(f.Path), Size: f.Size, Hash: f.Hash, Reason: reason, Remote: &f}
+}
+
+func deleteRemoteStep(c fileChange, reason string) SyncStep {
+	return SyncStep{Action: SyncDeleteRemote, Path: c.Path, Size: c.Remote.Size, Reason: reason}
+}
+
+func deleteLocalStep(c fileChange, reason string) SyncStep {
+	return SyncStep{Action: SyncDeleteLocal, Path: c.Path, Size: c.Local.Size, Reason: reason}
+}
+
+// conflictCopyPath inserts the suffix before the extension, docs/a.txt becomes docs/a.conflict-<suffix>.txt
+func conflictCopyPath(p string, suffix string) string {
+	ext := path.Ext(p)
+	if ext == path.Base(p) {
+		// Dot files like .env have no extension
+		ext = ""
+	}
+	return fmt.Sprintf("%s.conflict-%s%s", strings.TrimSuffix(p, ext), suffix, ext)
+}
+
+// getConflictSuffix names conflict copies after the machine and the time of the sync
+func getConflictSuffix(now time.Time) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "local"
+	}
+	return fmt.Sprintf("%s-%s", host, now.Format("20060102-150405"))
+}
+
+// mergeStep resolves a change without a preferred side: a change on one side only is copied to the other one,
+// for conflicts the newer version wins and a modification wins over a deletion.
+// Local and server clocks may differ, so the losing version of a file changed on both sides is kept as a conflict copy.
+func mergeStep(c fileChange, conflictSuffix string) SyncStep {
+	if !c.IsConflict() {
+		switch {
+		case c.LocalChange == ChangeDeleted:
+			return deleteRemoteStep(c, "deleted locally")
+		case c.LocalChange != ChangeNone:
+			return uploadStep(*c.Local, fmt.Sprintf("%s locally", c.LocalChange))
+		case c.RemoteChange == ChangeDeleted:
+			return deleteLocalStep(c, "deleted on server")
+		default:
+			return downloadStep(*c.Remote, fmt.Sprintf("%s on server", c.RemoteChange))
+		}
+	}
+
+	switch {
+	case c.Remote == nil:
+		return uploadStep(*c.Local, "conflict, deleted on server")
+	case c.Local == nil:
+		return downloadStep(*c.Remote, "conflict, deleted locally")
+	}
+
+	var step SyncStep
+	if c.Local.ModTime.After(c.Remote.UpdatedTime()) {
+		step = uploadStep(*c.Local, "conflict, local is newer")
+	} else {
+		step = downloadStep(*c.Remote, "conflict, server is newer")
+	}
+	step.ConflictCopy = conflictCopyPath(c.Path, conflictSuffix)
+	return step
+}
+
+// planDirs creates directories missing on one side, with prefer the directories missing on the preferred side are deleted
+func planDirs(local map[string]localFile, remote map[string]api.FileResponse, prefer string) []SyncStep {
+	// The server may list files without DIR entries of their parents
+	remoteDirs := map[string]bool{}
+	for p, r := range remote {
+		if r.FileType == api.Dir {
+			remoteDirs[p] = true
+		}
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			remoteDirs[dir] = true
+		}
+	}
+
+	var steps []SyncStep
+	for p, r := range remote {
+		if _, exists := local[p]; r.FileType != api.Dir || exists {
+			continue
+		}
+		if prefer == PreferLocal {
+			steps = append(steps, SyncStep{Action: SyncDeleteRemote, Path: p, Reason: "only on server"})
+		} else {
+			steps = append(steps, SyncStep{Action: SyncMkdir, Path: p, Reason: "only on server"})
+		}
+	}
+	for p, l := range local {
+		if !l.IsDir || remoteDirs[p] {
+			continue
+		}
+		if prefer == PreferRemote {
+			steps = append(steps, SyncStep{Action: SyncDeleteLocal, Path: p, Reason: "only local"})
+		} else {
+			steps = append(steps, SyncStep{Action: SyncMkdirRemote, Path: p, Reason: "only local"})
+		}
+	}
+	return steps
+}
+
+// planSync turns the difference of the local tree and the server files into operations.
+// Without prefer both sides are merged, see mergeStep, otherwise the preferred side is mirrored.
+// Creations run parents first and deletions children first.
+func planSync(local map[string]localFile, remote map[string]api.FileResponse, index *config.Index, prefer string, conflictSuffix string) []SyncStep {
+	steps := planDirs(local, remote, prefer)
+
+	for _, c := range diffTrees(local, remote, index) {
+		switch {
+		case prefer == PreferLocal && c.Local != nil:
+			steps = append(steps, uploadStep(*c.Local, "changed"))
+		case prefer == PreferLocal:
+			steps = append(steps, deleteRemoteStep(c, "only on server"))
+		case prefer == PreferRemote && c.Remote != nil:
+			steps = append(steps, downloadStep(*c.Remote, "changed"))
+		case prefer == PreferRemote:
+			steps = append(steps, deleteLocalStep(c, "only local"))
+		default:
+			steps = append(steps, mergeStep(c, conflictSuffix))
+		}
+	}
+
+	sort.Slice(steps, func(i, j int) bool {
+		a, b := helpers.IndexOf(syncActionOrder, steps[i].Action), helpers.IndexOf(syncActionOrder, steps[j].Action)
+		if a != b {
+			return a < b
+		}
+		if steps[i].Action == SyncDeleteLocal || steps[i].Action == SyncDeleteRemote {
+			return steps[i].Path > steps[j].Path
+		}
+		return steps[i].Path < steps[j].Path
+	})
+	return steps
+}
+
+func isRemoteWrite(step SyncStep) bool {
+	return step.Action == SyncMkdirRemote || step.Action == SyncUpload || step.Action == SyncDeleteRemote
+}
+
+// checkUpload refuses uploads breaking the folder rules before they are rejected by the server
+func checkUpload(rules policy.Rules, root string, step SyncStep) error {
+	if step.Action != SyncUpload && step.Action != SyncMkdirRemote {
+		return nil
+	}
+	target, err := helpers.SafeJoin(root, step.Path)
+	if err != nil {
+		return err
+	}
+	violations, err := rules.CheckLocalFile(target, step.Path)
+	if err != nil {
+		return err
+	}
+	if len(violations) != 0 {
+		return helpers.UsageError("breaks the folder rules: %s", policy.FormatViolations(violations))
+	}
+	return nil
+}
+
+// applySyncStep performs the step and updates the index entry of its path
+func applySyncStep(step SyncStep, folderId string, root string, accessToken string, index *config.Index) error {
+	target, err := helpers.SafeJoin(root, step.Path)
+	if err != nil {
+		return err
+	}
+	switch step.Action {
+	case SyncMkdir:
+		return os.MkdirAll(target, os.ModePerm)
+	case SyncMkdirRemote:
+		_, err := api.FolderDirCreate(folderId, step.Path, accessToken)
+		return err
+	case SyncDownload:
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if step.ConflictCopy != "" {
+			if err := keepLocalConflictCopy(root, target, step.ConflictCopy); err != nil {
+				return err
+			}
+		}
+		if err := api.FolderFileDownload(folderId, step.Path, step.Hash, step.Size, accessToken, target, nil); err != nil {
+			return err
+		}
+		if err := setModTime(target, *step.Remote); err != nil {
+			return err
+		}
+		return index.Set(step.Path, target, step.Remote.Hash, step.Remote.SherryFileID, step.Remote.UpdatedAt)
+	case SyncUpload:
+		if step.ConflictCopy != "" {
+			if err := keepRemoteConflictCopy(folderId, root, step, accessToken); err != nil {
+				return err
+			}
+		}
+		response, err := api.FolderFileUpload(folderId, step.Path, target, step.Hash, step.Size, accessToken)
+		if err != nil {
+			return err
+		}
+		return index.Set(step.Path, target, step.Hash, response.SherryFileID, response.UpdatedAt)
+	case SyncDeleteLocal:
+		// Directories are removed after their files, one still holding ignored files is reported
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+		delete(index.Files, step.Path)
+	case SyncDeleteRemote:
+		if err := api.FolderFileDelete(folderId, step.Path, accessToken); err != nil {
+			return err
+		}
+		delete(index.Files, step.Path)
+	}
+	return nil
+}
+
+// keepLocalConflictCopy renames the local version of a conflict before it is overwritten by the download
+func keepLocalConflictCopy(root string, target string, conflictCopy string) error {
+	copyPath, err := helpers.SafeJoin(root, conflictCopy)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(target, copyPath); err != nil {
+		return fmt.Errorf("can't keep conflict copy %s: %w", conflictCopy, err)
+	}
+	return nil
+}
+
+// keepRemoteConflictCopy downloads the server version of a conflict before it is overwritten by the upload
+func keepRemoteConflictCopy(folderId string, root string, step SyncStep, accessToken string) error {
+	copyPath, err := helpers.SafeJoin(root, step.ConflictCopy)
+	if err != nil {
+		return err
+	}
+	if err := api.FolderFileDownload(folderId, step.Path, "", 0, accessToken, copyPath, nil); err != nil {
+		return fmt.Errorf("can't keep conflict copy %s: %w", step.ConflictCopy, err)
+	}
+	return nil
+}
+
+// refreshIndex records files equal on both sides, e.g. synchronized by the demon or copied manually,
+// and drops entries of files deleted on both sides
+func refreshIndex(root string, local map[string]localFile, remote map[string]api.FileResponse, index *config.Index) {
+	for p := range index.Files {
+		_, isLocal := local[p]
+		_, isRemote := remote[p]
+		if !isLocal && !isRemote {
+			delete(index.Files, p)
+		}
+	}
+	for p, r := range remote {
+		l, exists := local[p]
+		if !exists || l.IsDir || r.FileType == api.Dir || l.Hash != r.Hash {
+			continue
+		}
+		if base, ok := index.Files[p]; ok && base.Size == l.Size && base.ModTime.Equal(l.ModTime) && base.UpdatedAt == r.UpdatedAt {
+			continue
+		}
+		if target, err := helpers.SafeJoin(root, p); err == nil {
+			_ = index.Set(p, target, r.Hash, r.SherryFileID, r.UpdatedAt)
+		}
+	}
+}
+
+func printSyncResult(result SyncResult) {
+	if len(result.Steps) == 0 {
+		helpers.PrintMessage(fmt.Sprintf("%s is up to date", result.LocalPath))
+		return
+	}
+	for _, step := range result.Steps {
+		reason := step.Reason
+		if step.ConflictCopy != "" {
+			reason = fmt.Sprintf("%s, other version kept as %s", reason, step.ConflictCopy)
+		}
+		line := fmt.Sprintf("  %-14s %s (%s, %s)", step.Action, step.Path, humanize.Bytes(step.Size), reason)
+		if step.Error != "" {
+			line = helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, fmt.Sprintf("%s: %s", line, step.Error))
+		}
+		helpers.PrintMessage(line)
+	}
+	if result.DryRun {
+		helpers.PrintMessage(fmt.Sprintf("%d operations planned, nothing was changed", len(result.Steps)))
+	} else {
+		helpers.PrintMessage(fmt.Sprintf("%d operations done, %d failed", len(result.Steps)-result.Failed, result.Failed))
+	}
+}
+
+func SyncSharedFolder(localPath string, dryRun bool, prefer string) (bool, error) {
+	state, err := loadFolderState(localPath)
+	if err != nil {
+		return false, err
+	}
+	source, root, index := state.Source, state.Root, state.Index
+
+	steps := planSync(state.Local, state.Remote, index, prefer, getConflictSuffix(time.Now()))
+	if source.Access == api.PermissionRoleRead && helpers.Find(steps, isRemoteWrite) != nil {
+		return false, helpers.UsageError("You have read access to %s and can't upload changes, use --prefer remote to discard them", source.Name)
+	}
+
+	result := SyncResult{
+		Folder:    source.Name,
+		LocalPath: root,
+		DryRun:    dryRun,
+		Steps:     helpers.EmptyIfNull(steps),
+	}
+
+	var firstErr error
+	if !dryRun {
+		helpers.PrintMessage(fmt.Sprintf("Syncing %s at %s", source.Name, root))
+		refreshIndex(root, state.Local, state.Remote, index)
+		rules := policy.FromSource(source)
+		for i, step := range result.Steps {
+			err := checkUpload(rules, root, step)
+			if err == nil {
+				err = applySyncStep(step, source.Id, root, state.Credentials.AccessToken, index)
+			}
+			if err != nil {
+				result.Steps[i].Error = err.Error()
+				result.Failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		if err := config.CommitIndex(state.Watcher.HashesId, index); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	helpers.PrintResult(result, func() {
+		printSyncResult(result)
+	})
+
+	if firstErr != nil {
+		return false, helpers.WrapError(helpers.GetExitCode(firstErr), firstErr, fmt.Sprintf("%d of %d operations failed", result.Failed, len(result.Steps)))
+	}
+	return false, nil
+}
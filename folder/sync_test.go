@@ -0,0 +1,208 @@
+package folder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sherry/shr/api"
+	"sherry/shr/config"
+	"testing"
+	"time"
+)
+
+func TestPlanSync(t *testing.T) {
+	old := time.UnixMilli(1000)
+	recent := time.UnixMilli(2000)
+
+	localFiles := func(files ...localFile) map[string]localFile {
+		result := map[string]localFile{}
+		for _, f := range files {
+			result[f.Path] = f
+		}
+		return result
+	}
+	remoteFiles := func(files ...api.FileResponse) map[string]api.FileResponse {
+		return remoteFilesByPath(files, nil)
+	}
+	indexOf := func(entries ...config.IndexEntry) *config.Index {
+		index := config.NewIndex()
+		for _, e := range entries {
+			index.Files[e.Path] = e
+		}
+		return index
+	}
+
+	tests := []struct {
+		name   string
+		local  map[string]localFile
+		remote map[string]api.FileResponse
+		index  *config.Index
+		prefer string
+		want   []SyncStep
+	}{
+		{
+			name:   "Test equal files",
+			local:  localFiles(localFile{Path: "a", Hash: "1"}),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "1"}),
+			index:  indexOf(),
+		},
+		{
+			name:   "Test added on both sides",
+			local:  localFiles(localFile{Path: "a", Hash: "1"}),
+			remote: remoteFiles(api.FileResponse{Path: "b", Hash: "2"}),
+			index:  indexOf(),
+			want: []SyncStep{
+				{Action: SyncDownload, Path: "b", Hash: "2", Reason: "added on server"},
+				{Action: SyncUpload, Path: "a", Hash: "1", Reason: "added locally"},
+			},
+		},
+		{
+			name:   "Test changed locally",
+			local:  localFiles(localFile{Path: "a", Hash: "2"}),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "1"}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncUpload, Path: "a", Hash: "2", Reason: "modified locally"}},
+		},
+		{
+			name:   "Test changed on server",
+			local:  localFiles(localFile{Path: "a", Hash: "1"}),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "2"}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncDownload, Path: "a", Hash: "2", Reason: "modified on server"}},
+		},
+		{
+			name:   "Test deleted locally",
+			local:  localFiles(),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "1"}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncDeleteRemote, Path: "a", Reason: "deleted locally"}},
+		},
+		{
+			name:   "Test deleted on server",
+			local:  localFiles(localFile{Path: "a", Hash: "1"}),
+			remote: remoteFiles(),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncDeleteLocal, Path: "a", Reason: "deleted on server"}},
+		},
+		{
+			name:   "Test modification wins over deletion",
+			local:  localFiles(localFile{Path: "a", Hash: "2"}),
+			remote: remoteFiles(),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncUpload, Path: "a", Hash: "2", Reason: "conflict, deleted on server"}},
+		},
+		{
+			name:   "Test newer side wins conflict",
+			local:  localFiles(localFile{Path: "a", Hash: "2", ModTime: old}),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "3", UpdatedAt: uint64(recent.UnixMilli())}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncDownload, Path: "a", Hash: "3", Reason: "conflict, server is newer", ConflictCopy: "a.conflict-host-1"}},
+		},
+		{
+			name:   "Test local newer wins conflict",
+			local:  localFiles(localFile{Path: "docs/a.txt", Hash: "2", ModTime: recent}),
+			remote: remoteFiles(api.FileResponse{Path: "docs/a.txt", Hash: "3", UpdatedAt: uint64(old.UnixMilli())}),
+			index:  indexOf(config.IndexEntry{Path: "docs/a.txt", Hash: "1"}),
+			want: []SyncStep{
+				{Action: SyncUpload, Path: "docs/a.txt", Hash: "2", Reason: "conflict, local is newer", ConflictCopy: "docs/a.conflict-host-1.txt"},
+			},
+		},
+		{
+			name:   "Test changed on both sides without index",
+			local:  localFiles(localFile{Path: "a.txt", Hash: "2", ModTime: old}),
+			remote: remoteFiles(api.FileResponse{Path: "a.txt", Hash: "3", UpdatedAt: uint64(old.UnixMilli())}),
+			index:  indexOf(),
+			want: []SyncStep{
+				{Action: SyncDownload, Path: "a.txt", Hash: "3", Reason: "conflict, server is newer", ConflictCopy: "a.conflict-host-1.txt"},
+			},
+		},
+		{
+			name:   "Test modification on server wins over local deletion",
+			local:  localFiles(),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "2"}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "1"}),
+			want:   []SyncStep{{Action: SyncDownload, Path: "a", Hash: "2", Reason: "conflict, deleted locally"}},
+		},
+		{
+			name:   "Test prefer local mirrors local files",
+			local:  localFiles(localFile{Path: "a", Hash: "1"}),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "2"}, api.FileResponse{Path: "b", Hash: "3"}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "2"}),
+			prefer: PreferLocal,
+			want: []SyncStep{
+				{Action: SyncUpload, Path: "a", Hash: "1", Reason: "changed"},
+				{Action: SyncDeleteRemote, Path: "b", Reason: "only on server"},
+			},
+		},
+		{
+			name:  "Test prefer local deletes server only directories",
+			local: localFiles(localFile{Path: "e", IsDir: true}),
+			remote: remoteFiles(
+				api.FileResponse{Path: "d", FileType: api.Dir},
+				api.FileResponse{Path: "d/x", Hash: "1"},
+				api.FileResponse{Path: "e", FileType: api.Dir},
+			),
+			index:  indexOf(),
+			prefer: PreferLocal,
+			want: []SyncStep{
+				{Action: SyncDeleteRemote, Path: "d/x", Reason: "only on server"},
+				{Action: SyncDeleteRemote, Path: "d", Reason: "only on server"},
+			},
+		},
+		{
+			name: "Test local only directories are created on server",
+			local: localFiles(
+				localFile{Path: "d", IsDir: true},
+				localFile{Path: "d/e", IsDir: true},
+				localFile{Path: "d/a", Hash: "1"},
+				localFile{Path: "f", IsDir: true},
+				localFile{Path: "f/b", Hash: "2"},
+			),
+			remote: remoteFiles(api.FileResponse{Path: "f/b", Hash: "2"}),
+			index:  indexOf(),
+			want: []SyncStep{
+				{Action: SyncMkdirRemote, Path: "d", Reason: "only local"},
+				{Action: SyncMkdirRemote, Path: "d/e", Reason: "only local"},
+				{Action: SyncUpload, Path: "d/a", Hash: "1", Reason: "added locally"},
+			},
+		},
+		{
+			name:   "Test prefer remote deletes local only directories",
+			local:  localFiles(localFile{Path: "d", IsDir: true}, localFile{Path: "d/a", Hash: "1"}),
+			remote: remoteFiles(),
+			index:  indexOf(),
+			prefer: PreferRemote,
+			want: []SyncStep{
+				{Action: SyncDeleteLocal, Path: "d/a", Reason: "only local"},
+				{Action: SyncDeleteLocal, Path: "d", Reason: "only local"},
+			},
+		},
+		{
+			name:   "Test prefer remote mirrors server",
+			local:  localFiles(localFile{Path: "a", Hash: "2"}, localFile{Path: "b", Hash: "1"}),
+			remote: remoteFiles(api.FileResponse{Path: "a", Hash: "1"}, api.FileResponse{Path: "d", FileType: api.Dir}),
+			index:  indexOf(config.IndexEntry{Path: "a", Hash: "2"}),
+			prefer: PreferRemote,
+			want: []SyncStep{
+				{Action: SyncMkdir, Path: "d", Reason: "only on server"},
+				{Action: SyncDownload, Path: "a", Hash: "1", Reason: "changed"},
+				{Action: SyncDeleteLocal, Path: "b", Reason: "only local"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := planSync(tt.local, tt.remote, tt.index, tt.prefer, "host-1")
+			for i := range steps {
+				steps[i].Remote = nil
+			}
+			assert.Equal(t, tt.want, steps)
+		})
+	}
+}
+
+func TestConflictCopyPath(t *testing.T) {
+	assert.Equal(t, "a.conflict-host-1.txt", conflictCopyPath("a.txt", "host-1"))
+	assert.Equal(t, "docs/report.conflict-host-1.pdf", conflictCopyPath("docs/report.pdf", "host-1"))
+	assert.Equal(t, "archive.tar.conflict-host-1.gz", conflictCopyPath("archive.tar.gz", "host-1"))
+	assert.Equal(t, "Makefile.conflict-host-1", conflictCopyPath("Makefile", "host-1"))
+	assert.Equal(t, "config/.env.conflict-host-1", conflictCopyPath("config/.env", "host-1"))
+}
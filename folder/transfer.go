@@ -0,0 +1,185 @@
+package folder
+
+import (
+	"fmt"
+	"github.com/erikgeiser/promptkit/confirmation"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"strings"
+)
+
+// KeepNone removes the access of the previous owner after the transfer
+const KeepNone = "none"
+
+type TransferResult = struct {
+	Folder        string `json:"folder"`
+	Id            string `json:"id"`
+	OwnerId       string `json:"ownerId"`
+	Owner         string `json:"owner"`
+	PreviousOwner string `json:"previousOwner"`
+	// Role is kept by the previous owner, empty if the access was removed
+	Role string `json:"role,omitempty"`
+	// ReassignedGrants are expiring grants now revoked by the sweep with credentials of the new owner,
+	// DroppedGrants are no longer tracked because the new owner is not logged in on this machine
+	ReassignedGrants int `json:"reassignedGrants"`
+	DroppedGrants    int `json:"droppedGrants"`
+}
+
+// transferExpiringGrants forgets the expiry of the new owner and hands the grants of the previous owner
+// to the new one, or drops them if reassign is false. It returns the number of reassigned and dropped grants.
+func transferExpiringGrants(ledger *config.ExpiryLedger, folderId string, previousOwnerId string, ownerId string, reassign bool) (int, int) {
+	ledger.Remove(folderId, ownerId)
+
+	var grants []config.ExpiringGrant
+	reassigned, dropped := 0, 0
+	for _, g := range ledger.Grants {
+		if g.FolderId == folderId && g.GrantedBy == previousOwnerId {
+			if !reassign {
+				dropped++
+				continue
+			}
+			g.GrantedBy = ownerId
+			reassigned++
+		}
+		grants = append(grants, g)
+	}
+	ledger.Grants = helpers.EmptyIfNull(grants)
+	return reassigned, dropped
+}
+
+// updateTransferredSources updates the cached sources of every local user, the previous owner keeps the role
+// or loses the folder with its watchers when role is empty
+func updateTransferredSources(sherryId string, ownerId string, previousOwnerId string, role string) error {
+	conf := config.GetConfig()
+	for key, s := range conf.Sources {
+		if s.Id != sherryId {
+			continue
+		}
+		s.OwnerId = ownerId
+		switch s.UserId {
+		case ownerId:
+			s.Access = api.PermissionRoleOwner
+		case previousOwnerId:
+			s.Access = role
+		}
+		conf.Sources[key] = s
+	}
+
+	if role != "" {
+		return nil
+	}
+	_, err := removeSourcesFromConfig(sherryId, func(s config.Source) bool {
+		return s.UserId == previousOwnerId
+	})
+	return err
+}
+
+func TransferSharedFolder(user string, yes bool, folder string, to string, keep string) (bool, error) {
+	credentials, err := auth.FindActiveUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := getOwnedFolder(folder, *credentials)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := getTargetUser(to, credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+	if target.UserId == credentials.UserId {
+		return false, helpers.UsageError("You already own %s", response.Name)
+	}
+
+	role := strings.ToUpper(keep)
+	if keep == KeepNone {
+		role = ""
+	}
+
+	if !yes {
+		kept := helpers.If(role != "", func() string {
+			return fmt.Sprintf("you will keep %s access", role)
+		}, func() string {
+			return "you will lose access"
+		})
+		confirmed, err := helpers.Confirmation(fmt.Sprintf("Transfer %s to %s, %s?", response.Name, target.Username, kept), "--yes", "", confirmation.No)
+		if err != nil {
+			return false, err
+		}
+		if !confirmed {
+			return false, helpers.FailureError("Aborting...")
+		}
+	}
+
+	err = api.FolderPermission(response.SherryId, target.UserId, api.PayloadFolderPermission{
+		Role:   api.PermissionRoleOwner,
+		Action: api.PermissionActionGrant,
+	}, credentials.AccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	// Only the owner can revoke expiring grants, without local credentials of the new owner they are dropped
+	reassign := auth.GetUserById(target.UserId) != nil
+	var reassigned, dropped int
+	err = config.UpdateExpiryLedger(func(ledger *config.ExpiryLedger) {
+		reassigned, dropped = transferExpiringGrants(ledger, response.SherryId, credentials.UserId, target.UserId, reassign)
+	})
+	if err != nil {
+		return true, err
+	}
+
+	// The folder is already transferred, the cache is updated even if changing the own role fails
+	var permissionErr error
+	if role == "" {
+		permissionErr = api.FolderPermissionRevoke(response.SherryId, credentials.UserId, credentials.AccessToken)
+	} else {
+		permissionErr = api.FolderPermission(response.SherryId, credentials.UserId, api.PayloadFolderPermission{
+			Role:   role,
+			Action: api.PermissionActionGrant,
+		}, credentials.AccessToken)
+	}
+	if permissionErr != nil {
+		role = api.PermissionRoleOwner
+	}
+	if err := updateTransferredSources(response.SherryId, target.UserId, credentials.UserId, role); err != nil {
+		return true, err
+	}
+	if permissionErr != nil {
+		return true, helpers.WrapError(helpers.GetExitCode(permissionErr), permissionErr, fmt.Sprintf(
+			"Folder was transferred to %s, but your access was not changed: %s", target.Username, permissionErr,
+		))
+	}
+
+	result := TransferResult{
+		Folder:           response.Name,
+		Id:               response.SherryId,
+		OwnerId:          target.UserId,
+		Owner:            target.Username,
+		PreviousOwner:    credentials.Username,
+		Role:             role,
+		ReassignedGrants: reassigned,
+		DroppedGrants:    dropped,
+	}
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("Folder %s was transferred to %s", result.Folder, result.Owner))
+		if result.Role != "" {
+			helpers.PrintMessage(fmt.Sprintf("You have %s access now", result.Role))
+		}
+		if result.ReassignedGrants != 0 {
+			helpers.PrintMessage(fmt.Sprintf("%d expiring permissions will be revoked with credentials of %s", result.ReassignedGrants, result.Owner))
+		}
+		if result.DroppedGrants != 0 {
+			helpers.PrintMessage(helpers.WithColor([]int{helpers.ConsoleFgDarkYellow}, fmt.Sprintf(
+				"%d expiring permissions are no longer tracked, %s is not logged in on this machine", result.DroppedGrants, result.Owner,
+			)))
+		}
+		helpers.PrintMessage(fmt.Sprintf("Collaborators have to use %s:%s to get the folder", result.Owner, result.Folder))
+	})
+
+	return true, nil
+}
@@ -0,0 +1,192 @@
+package folder
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sherry/shr/api"
+	"sherry/shr/auth"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"sherry/shr/ignore"
+	"time"
+)
+
+// folderState is the local and remote state of a watched folder
+type folderState struct {
+	Watcher     config.Watcher
+	Source      config.Source
+	Credentials config.Credentials
+	Root        string
+	Index       *config.Index
+	Local       map[string]localFile
+	Remote      map[string]api.FileResponse
+}
+
+type localFile struct {
+	Path    string
+	Size    uint64
+	ModTime time.Time
+	IsDir   bool
+	Hash    string
+}
+
+// listLocalTree walks the watched directory and returns its entries keyed by slash separated relative path,
+// entries matched by ignored are skipped, ignored may be nil
+func listLocalTree(root string, ignored *ignore.Matcher) (map[string]localFile, error) {
+	files := map[string]localFile{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if ignored.Match(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := localFile{
+			Path:    filepath.ToSlash(rel),
+			ModTime: info.ModTime(),
+			IsDir:   d.IsDir(),
+		}
+		if !entry.IsDir {
+			// Links, sockets and other special files are not synchronized
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			entry.Size = uint64(info.Size())
+		}
+		files[entry.Path] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// scanLocalTree lists the watched directory and hashes its files.
+// Files with the size and modification time recorded in the index are not hashed again, index may be nil.
+func scanLocalTree(root string, index *config.Index, ignored *ignore.Matcher) (map[string]localFile, error) {
+	files, err := listLocalTree(root, ignored)
+	if err != nil {
+		return nil, err
+	}
+	for p, f := range files {
+		if f.IsDir {
+			continue
+		}
+		if hash, ok := index.UnchangedHash(p, f.Size, f.ModTime); ok {
+			f.Hash = hash
+		} else if f.Hash, err = helpers.HashFile(filepath.Join(root, filepath.FromSlash(p))); err != nil {
+			return nil, err
+		}
+		files[p] = f
+	}
+	return files, nil
+}
+
+// remoteFilesByPath returns the server files keyed by normalized path, entries matched by ignored are skipped
+func remoteFilesByPath(files []api.FileResponse, ignored *ignore.Matcher) map[string]api.FileResponse {
+	result := map[string]api.FileResponse{}
+	for _, f := range files {
+		p := helpers.NormalizePath(f.Path)
+		if ignored.Match(p, f.FileType == api.Dir) {
+			continue
+		}
+		result[p] = f
+	}
+	return result
+}
+
+// loadIgnore returns the patterns of the .sherryignore file and of the watcher
+func loadIgnore(root string, watcher config.Watcher) (*ignore.Matcher, error) {
+	matcher, err := ignore.Load(root, watcher.Ignore)
+	if err != nil {
+		return nil, helpers.FailureError("Can't read %s: %s", ignore.FileName, err)
+	}
+	return matcher, nil
+}
+
+func getIndexEntry(index *config.Index, p string) (config.IndexEntry, bool) {
+	if index == nil {
+		return config.IndexEntry{}, false
+	}
+	entry, ok := index.Files[p]
+	return entry, ok
+}
+
+// findWatchedFolder resolves the watcher containing localPath, the current directory by default
+func findWatchedFolder(localPath string) (*config.Watcher, *config.Source, error) {
+	if localPath == "" {
+		localPath = "."
+	}
+	localPath = helpers.PreparePath(localPath)
+
+	watcher, err := config.FindWatcher(localPath)
+	if err != nil {
+		return nil, nil, helpers.FailureError("Error while checking path")
+	}
+	if watcher == nil {
+		return nil, nil, helpers.NotFoundError("No watcher found for %s", localPath)
+	}
+	source, ok := config.GetConfig().Sources[watcher.Source]
+	if !ok {
+		return nil, nil, helpers.NotFoundError("Source of the watcher %s not found", watcher.LocalPath)
+	}
+	return watcher, &source, nil
+}
+
+// loadFolderState resolves the watcher containing localPath and reads the files of both sides
+func loadFolderState(localPath string) (*folderState, error) {
+	watcher, source, err := findWatchedFolder(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := auth.GetActiveUserById(watcher.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := api.FolderFiles(source.Id, credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := config.ReadIndex(watcher.HashesId)
+	if err != nil {
+		return nil, err
+	}
+
+	root := helpers.PreparePath(watcher.LocalPath)
+	ignored, err := loadIgnore(root, *watcher)
+	if err != nil {
+		return nil, err
+	}
+	local, err := scanLocalTree(root, index, ignored)
+	if err != nil {
+		return nil, helpers.FailureError("Can't read %s: %s", root, err)
+	}
+
+	return &folderState{
+		Watcher:     *watcher,
+		Source:      *source,
+		Credentials: *credentials,
+		Root:        root,
+		Index:       index,
+		Local:       local,
+		Remote:      remoteFilesByPath(*files, ignored),
+	}, nil
+}
@@ -0,0 +1,47 @@
+package group
+
+import (
+	flag "github.com/jessevdk/go-flags"
+	"sherry/shr/config"
+)
+
+type Options struct {
+	Create CreateOptions `command:"create" description:"Create group of users, use it as --target @name in permission grant and revoke"`
+	List   ListOptions   `command:"list" description:"List groups"`
+	Delete DeleteOptions `command:"delete" description:"Delete group"`
+}
+
+type CreateOptions struct {
+	Members string `long:"members" short:"m" description:"Comma separated usernames or ids of the members"`
+	Args    struct {
+		Name string `positional-arg-name:"name" description:"Group name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type ListOptions struct {
+}
+
+type DeleteOptions struct {
+	Args struct {
+		Name string `positional-arg-name:"name" description:"Group name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func ApplyCommand(cmd *flag.Command, options Options) error {
+	if cmd.Active.Name != "group" {
+		return nil
+	}
+
+	return config.WithCommit(func() (bool, error) {
+		switch cmd.Active.Active.Name {
+		case "create":
+			return CreateGroup(options.Create.Args.Name, options.Create.Members)
+		case "list":
+			return ListGroups()
+		case "delete":
+			return DeleteGroup(options.Delete.Args.Name)
+		default:
+			return false, nil
+		}
+	})
+}
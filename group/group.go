@@ -0,0 +1,132 @@
+package group
+
+import (
+	"fmt"
+	"sherry/shr/config"
+	"sherry/shr/helpers"
+	"sort"
+	"strings"
+)
+
+// Prefix marks a group in place of a user, e.g. --target @design
+const Prefix = "@"
+
+type Result = struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+func IsGroup(target string) bool {
+	return strings.HasPrefix(target, Prefix)
+}
+
+// GetMembers returns usernames or ids of the members of the group given as @name
+func GetMembers(target string) ([]string, error) {
+	name := strings.TrimPrefix(target, Prefix)
+	groups, err := config.ReadGroups()
+	if err != nil {
+		return nil, err
+	}
+	members, ok := groups.Groups[name]
+	if !ok {
+		return nil, helpers.NotFoundError("Group %s not found", name)
+	}
+	return members, nil
+}
+
+func CreateGroup(name string, members string) (bool, error) {
+	name = strings.TrimPrefix(name, Prefix)
+	name, err := helpers.Input("Group name", "name argument", name, helpers.IsWordValidator, "", false)
+	if err != nil {
+		return false, err
+	}
+	members, err = helpers.Input("Members", "--members", members, func(s string) error {
+		_, err := helpers.ParseValueArray("Members", s, helpers.IsWordOrIdValidator, "")
+		return err
+	}, "alice,bob", false)
+	if err != nil {
+		return false, err
+	}
+	values, err := helpers.ParseValueArray("Members", members, helpers.IsWordOrIdValidator, "")
+	if err != nil {
+		return false, err
+	}
+	if len(values) == 0 {
+		return false, helpers.UsageError("Group needs at least one member")
+	}
+
+	groups, err := config.ReadGroups()
+	if err != nil {
+		return false, err
+	}
+	if _, exists := groups.Groups[name]; exists {
+		return false, helpers.ConflictError("Group %s already exists", name)
+	}
+	groups.Groups[name] = values
+	if err := config.CommitGroups(groups); err != nil {
+		return false, err
+	}
+
+	result := Result{Name: name, Members: values}
+	helpers.PrintResult(result, func() {
+		helpers.PrintMessage(fmt.Sprintf("Group %s created, use it as --target %s%s", name, Prefix, name))
+	})
+
+	return false, nil
+}
+
+func ListGroups() (bool, error) {
+	groups, err := config.ReadGroups()
+	if err != nil {
+		return false, err
+	}
+	results := []Result{}
+	for name, members := range groups.Groups {
+		results = append(results, Result{Name: name, Members: members})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	helpers.PrintResult(results, func() {
+		if len(results) == 0 {
+			helpers.PrintMessage("No groups")
+			return
+		}
+		for _, r := range results {
+			helpers.PrintMessage(fmt.Sprintf("  %s%s: %s", Prefix, r.Name, strings.Join(r.Members, ", ")))
+		}
+	})
+
+	return false, nil
+}
+
+func DeleteGroup(name string) (bool, error) {
+	name = strings.TrimPrefix(name, Prefix)
+	groups, err := config.ReadGroups()
+	if err != nil {
+		return false, err
+	}
+	members, exists := groups.Groups[name]
+	if !exists {
+		return false, helpers.NotFoundError("Group %s not found", name)
+	}
+	delete(groups.Groups, name)
+	if err := config.CommitGroups(groups); err != nil {
+		return false, err
+	}
+
+	helpers.PrintResult(Result{Name: name, Members: members}, func() {
+		helpers.PrintMessage(fmt.Sprintf("Group %s deleted", name))
+	})
+
+	return false, nil
+}
+
+// IsTargetValidator accepts a username, a user id or a group as @name
+func IsTargetValidator(input string) error {
+	if IsGroup(input) {
+		return helpers.IsWordValidator(strings.TrimPrefix(input, Prefix))
+	}
+	return helpers.IsWordOrIdValidator(input)
+}
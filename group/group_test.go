@@ -0,0 +1,15 @@
+package group
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsTargetValidator(t *testing.T) {
+	for _, target := range []string{"bob", "@design", "3f2b8a9e-1c4d-4e5f-8a6b-7c8d9e0f1a2b"} {
+		assert.NoError(t, IsTargetValidator(target), target)
+	}
+	for _, target := range []string{"", "@", "@@design", "@de sign", "bob smith"} {
+		assert.Error(t, IsTargetValidator(target), target)
+	}
+}
@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"sherry/shr/constants"
+)
+
+// CommandError is an error that carries the process exit code it should be reported with
+type CommandError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+func NewError(code int, format string, args ...interface{}) error {
+	return &CommandError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WrapError attaches an exit code to err, an empty message keeps the message of err
+func WrapError(code int, err error, message string) error {
+	return &CommandError{Code: code, Message: message, Err: err}
+}
+
+func FailureError(format string, args ...interface{}) error {
+	return NewError(constants.ExitFailure, format, args...)
+}
+
+func UsageError(format string, args ...interface{}) error {
+	return NewError(constants.ExitUsage, format, args...)
+}
+
+func AuthExpiredError(format string, args ...interface{}) error {
+	return NewError(constants.ExitAuthExpired, format, args...)
+}
+
+func NotFoundError(format string, args ...interface{}) error {
+	return NewError(constants.ExitNotFound, format, args...)
+}
+
+func NetworkError(err error) error {
+	return WrapError(constants.ExitNetwork, err, "")
+}
+
+func ConflictError(format string, args ...interface{}) error {
+	return NewError(constants.ExitConflict, format, args...)
+}
+
+func InputRequiredError(format string, args ...interface{}) error {
+	return NewError(constants.ExitInputRequired, format, args...)
+}
+
+// GetExitCode returns the exit code the error should be reported with
+func GetExitCode(err error) int {
+	if err == nil {
+		return constants.ExitOk
+	}
+	var commandError *CommandError
+	if errors.As(err, &commandError) {
+		return commandError.Code
+	}
+	return constants.ExitFailure
+}
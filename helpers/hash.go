@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+)
+
+// NewHash returns the hash function used by the server for file contents
+func NewHash() hash.Hash {
+	return sha256.New()
+}
+
+func HashSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := NewHash()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return HashSum(h), nil
+}
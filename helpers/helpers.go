@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -80,8 +81,10 @@ func PrintErr(err string) {
 	_, _ = fmt.Fprintf(os.Stderr, "\n")
 }
 
+// PrintMessage prints progress and informational messages,
+// they are sent to stderr when structured output is selected.
 func PrintMessage(msg string) {
-	fmt.Println(msg)
+	printInfo(msg)
 }
 
 func PrintJson(data interface{}) {
@@ -100,19 +103,23 @@ func PrintMap(data interface{}, name string, omit []string) {
 		panic(e)
 	}
 
-	fmt.Println()
-	fmt.Println(fmt.Sprintf("%s:", name))
+	printInfo("")
+	printInfo(fmt.Sprintf("%s:", name))
 	for k, v := range jsonMap {
 		if Includes(omit, k) {
 			continue
 		}
-		fmt.Println(fmt.Sprintf("  %s: %s", ToTitle(k), v))
+		printInfo(fmt.Sprintf("  %s: %s", ToTitle(k), v))
 	}
-	fmt.Println()
+	printInfo("")
 }
 
-func Input(name string, value string, validator func(string) error, placeholder string, hide bool) string {
+// Input prompts for the value if it is empty, flag names the option to pass the value non-interactively
+func Input(name string, flag string, value string, validator func(string) error, placeholder string, hide bool) (string, error) {
 	if value == "" {
+		if err := requireInteractive(name, flag); err != nil {
+			return "", err
+		}
 		input := textinput.New(name)
 		input.Placeholder = placeholder
 		input.Validate = validator
@@ -120,80 +127,106 @@ func Input(name string, value string, validator func(string) error, placeholder
 		var e error
 		value, e = input.RunPrompt()
 		if e != nil {
-			PrintErr(e.Error())
-			os.Exit(1)
+			return "", FailureError(e.Error())
 		}
 	}
 	if validator(value) != nil {
-		PrintErr(fmt.Sprintf("Invalid %s: %s", name, value))
-		os.Exit(1)
+		return "", UsageError("Invalid %s: %s", name, value)
 	}
-	return value
+	return value, nil
 }
 
-func Select(name string, value string, options []string) string {
+func Select(name string, flag string, value string, options []string) (string, error) {
 	if value == "" {
+		if err := requireInteractive(name, flag); err != nil {
+			return "", err
+		}
 		selector := selection.New(name, options)
 		selector.PageSize = len(options)
 		var e error
 		value, e = selector.RunPrompt()
 		if e != nil {
-			PrintErr(e.Error())
-			os.Exit(1)
+			return "", FailureError(e.Error())
 		}
 	}
 	if !Includes(options, value) {
-		PrintErr(fmt.Sprintf("Invalid %s: %s", name, value))
-		os.Exit(1)
+		return "", UsageError("Invalid %s: %s", name, value)
 	}
-	return value
+	return value, nil
 }
 
-func ParseBool(name string, value string, def bool) bool {
+func ParseBool(name string, value string, def bool) (bool, error) {
 	if value == "" {
-		return def
+		return def, nil
 	}
 
 	value = strings.ToLower(value)
 	if value == "true" || value == "1" || value == "yes" {
-		return true
+		return true, nil
 	}
 	if value == "false" || value == "0" || value == "no" {
-		return false
+		return false, nil
 	}
 
-	PrintErr(fmt.Sprintf("Invalid %s: Can't parse \"%s\"", name, value))
-	os.Exit(1)
-	return false
+	return false, UsageError("Invalid %s: Can't parse \"%s\"", name, value)
 }
 
-func Confirmation(name string, value string, def *bool) bool {
+func Confirmation(name string, flag string, value string, def *bool) (bool, error) {
 	if value == "" {
+		if err := requireInteractive(name, flag); err != nil {
+			return false, err
+		}
 		input := confirmation.New(name, def)
 		decision, e := input.RunPrompt()
 		if e != nil {
-			PrintErr(e.Error())
-			os.Exit(1)
+			return false, FailureError(e.Error())
 		}
-		return decision
+		return decision, nil
 	}
 	return ParseBool(name, value, false)
 }
 
-func ParseDataSize(name string, size string, def uint64, max uint64) uint64 {
+func ParseDataSize(name string, size string, def uint64, max uint64) (uint64, error) {
 	if size == "" {
-		return def
+		return def, nil
 	}
 	bytes, err := humanize.ParseBytes(size)
 	if err != nil {
-		PrintErr(fmt.Sprintf("Invalid %s: Can't parse \"%s\"", name, size))
-		os.Exit(1)
+		return 0, UsageError("Invalid %s: Can't parse \"%s\"", name, size)
 	}
 	if max != 0 && bytes > max {
-		PrintErr(fmt.Sprintf("Invalid %s: Value \"%s\" is too large, max is \"%s\"", name, size, humanize.Bytes(max)))
-		os.Exit(1)
+		return 0, UsageError("Invalid %s: Value \"%s\" is too large, max is \"%s\"", name, size, humanize.Bytes(max))
+	}
+	return bytes, nil
+}
+
+var durationRegex = regexp.MustCompile(`(\d+)([mhdw])`)
+
+var durationUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseDuration parses durations like "7d", "2w" or "1d12h", units are m, h, d and w
+func ParseDuration(name string, value string) (time.Duration, error) {
+	matches := durationRegex.FindAllStringSubmatch(value, -1)
+	if len(matches) == 0 || durationRegex.ReplaceAllString(value, "") != "" {
+		return 0, UsageError("Invalid %s: Can't parse \"%s\"", name, value)
+	}
+	var duration time.Duration
+	for _, m := range matches {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, UsageError("Invalid %s: Can't parse \"%s\"", name, value)
+		}
+		duration += time.Duration(n) * durationUnits[m[2]]
+	}
+	if duration <= 0 {
+		return 0, UsageError("Invalid %s: Value \"%s\" must be positive", name, value)
 	}
-	return bytes
+	return duration, nil
 }
 
 var separator = regexp.MustCompile(`,\s*`)
@@ -202,7 +235,7 @@ func ToJoinedValues(values []string) string {
 	return strings.Join(values, ",")
 }
 
-func ParseValueArray(name string, value string, itemValidator func(string) error, def string) []string {
+func ParseValueArray(name string, value string, itemValidator func(string) error, def string) ([]string, error) {
 	if value == "" {
 		value = def
 	}
@@ -212,12 +245,11 @@ func ParseValueArray(name string, value string, itemValidator func(string) error
 			continue
 		}
 		if itemValidator(v) != nil {
-			PrintErr(fmt.Sprintf("Invalid %s: Can't parse \"%s\"", name, v))
-			os.Exit(1)
+			return nil, UsageError("Invalid %s: Can't parse \"%s\"", name, v)
 		}
 		values = append(values, v)
 	}
-	return values
+	return values, nil
 }
 
 func If[V any](condition bool, yes func() V, no func() V) V {
@@ -260,6 +292,24 @@ func Includes[T comparable](ts []T, item T) bool {
 	return false
 }
 
+func IndexOf[T comparable](ts []T, item T) int {
+	for i, t := range ts {
+		if t == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func IndexOfFunc[T any](ts []T, predicate func(T) bool) int {
+	for i, t := range ts {
+		if predicate(t) {
+			return i
+		}
+	}
+	return -1
+}
+
 func Map[T, U any](ts []T, f func(T) U) []U {
 	us := make([]U, len(ts))
 	for i := range ts {
@@ -280,6 +330,25 @@ func PreparePath(path string) string {
 	return NormalizePath(abs)
 }
 
+// SafeJoin joins a slash separated relative path received from the server to root
+// and refuses absolute paths and paths escaping root through ".."
+func SafeJoin(root string, rel string) (string, error) {
+	parts := strings.FieldsFunc(rel, func(r rune) bool {
+		return r == '/' || r == '\\'
+	})
+	// Drive letters are rejected on every platform, the path may come from a Windows client
+	isDrive := len(parts) != 0 && regexp.MustCompile(`^[a-zA-Z]:`).MatchString(parts[0])
+	if len(parts) == 0 || isDrive || strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, "\\") || filepath.IsAbs(rel) {
+		return "", FailureError("Refusing unsafe path %q", rel)
+	}
+	for _, part := range parts {
+		if part == ".." {
+			return "", FailureError("Refusing unsafe path %q", rel)
+		}
+	}
+	return filepath.Join(append([]string{root}, parts...)...), nil
+}
+
 func GetPathParts(path string) []string {
 	return strings.Split(NormalizePath(path), "/")
 }
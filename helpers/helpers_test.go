@@ -0,0 +1,68 @@
+package helpers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSafeJoin(t *testing.T) {
+	root := filepath.Join("data", "folder")
+	tests := []struct {
+		name string
+		rel  string
+		want string
+	}{
+		{name: "Test file in root", rel: "a.txt", want: filepath.Join(root, "a.txt")},
+		{name: "Test nested file", rel: "sub/dir/b.txt", want: filepath.Join(root, "sub", "dir", "b.txt")},
+		{name: "Test backslash separators", rel: "sub\\b.txt", want: filepath.Join(root, "sub", "b.txt")},
+		{name: "Test dot segments", rel: "./sub/./b.txt", want: filepath.Join(root, "sub", "b.txt")},
+		{name: "Test parent segment", rel: "../b.txt"},
+		{name: "Test nested parent segment", rel: "sub/../../b.txt"},
+		{name: "Test backslash parent segment", rel: "sub\\..\\..\\b.txt"},
+		{name: "Test absolute path", rel: "/etc/passwd"},
+		{name: "Test absolute windows path", rel: "C:\\Windows\\b.txt"},
+		{name: "Test drive relative windows path", rel: "C:b.txt"},
+		{name: "Test empty path", rel: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SafeJoin(root, tt.rel)
+			if tt.want == "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30m", 30 * time.Minute},
+		{"12h", 12 * time.Hour},
+		{"7d", 7 * day},
+		{"2w", 14 * day},
+		{"1d12h", day + 12*time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseDuration("Expiry", test.value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+
+	for _, value := range []string{"", "7", "d", "7x", "-1d", "7d ", "0d", "1.5d"} {
+		t.Run("invalid "+value, func(t *testing.T) {
+			_, err := ParseDuration("Expiry", value)
+			assert.Error(t, err)
+		})
+	}
+}
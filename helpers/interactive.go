@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"golang.org/x/term"
+	"os"
+)
+
+var interactive = true
+
+func SetInteractive(value bool) {
+	interactive = value
+}
+
+// IsInteractive reports whether prompts may be shown to the user
+func IsInteractive() bool {
+	return interactive
+}
+
+func IsStdinTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func requireInteractive(name string, flag string) error {
+	if interactive {
+		return nil
+	}
+	return InputRequiredError("Missing required value: %s, pass it with %s", name, flag)
+}
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"github.com/erikgeiser/promptkit/confirmation"
+	"github.com/stretchr/testify/assert"
+	"sherry/shr/constants"
+	"testing"
+)
+
+func TestNonInteractive(t *testing.T) {
+	SetInteractive(false)
+	t.Cleanup(func() { SetInteractive(true) })
+
+	tests := []struct {
+		name   string
+		flag   string
+		prompt func() error
+	}{
+		{name: "Test input", flag: "--email", prompt: func() error {
+			_, err := Input("Email", "--email", "", IsEmailValidator, "", false)
+			return err
+		}},
+		{name: "Test select", flag: "--access", prompt: func() error {
+			_, err := Select("Access", "--access", "", []string{"READ", "WRITE"})
+			return err
+		}},
+		{name: "Test confirmation", flag: "--yes", prompt: func() error {
+			_, err := Confirmation("Delete?", "--yes", "", confirmation.No)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prompt()
+			assert.Equal(t, constants.ExitInputRequired, GetExitCode(err))
+			assert.ErrorContains(t, err, tt.flag)
+		})
+	}
+
+	t.Run("Test given value", func(t *testing.T) {
+		value, err := Select("Access", "--access", "READ", []string{"READ", "WRITE"})
+		assert.NoError(t, err)
+		assert.Equal(t, "READ", value)
+	})
+}
@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+type OutputFormat = string
+
+const (
+	OutputTable OutputFormat = "table"
+	OutputJson  OutputFormat = "json"
+	OutputYaml  OutputFormat = "yaml"
+)
+
+var outputFormat = OutputTable
+
+func SetOutputFormat(format OutputFormat) {
+	if format == "" {
+		format = OutputTable
+	}
+	outputFormat = format
+}
+
+func GetOutputFormat() OutputFormat {
+	return outputFormat
+}
+
+// IsStructuredOutput reports whether commands should emit a single machine-readable document
+func IsStructuredOutput() bool {
+	return outputFormat == OutputJson || outputFormat == OutputYaml
+}
+
+// normalizeNumbers converts decoded json.Number values to integers where possible,
+// so they are not rendered in exponent notation
+func normalizeNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = normalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeNumbers(item)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return v
+}
+
+func PrintYaml(data interface{}) {
+	// Round trip through JSON to keep the json field names
+	var doc interface{}
+	dataJson, _ := json.Marshal(data)
+	decoder := json.NewDecoder(bytes.NewReader(dataJson))
+	decoder.UseNumber()
+	_ = decoder.Decode(&doc)
+	dataYaml, _ := yaml.Marshal(normalizeNumbers(doc))
+	fmt.Print(string(dataYaml))
+}
+
+// PrintResult prints data in the selected structured format or calls table for human-readable output
+func PrintResult(data interface{}, table func()) {
+	switch outputFormat {
+	case OutputJson:
+		PrintJson(data)
+	case OutputYaml:
+		PrintYaml(data)
+	default:
+		table()
+	}
+}
+
+func printInfo(msg string) {
+	if IsStructuredOutput() {
+		_, _ = fmt.Fprintln(os.Stderr, msg)
+		return
+	}
+	fmt.Println(msg)
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"golang.org/x/term"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressWidth    = 30
+	progressInterval = 100 * time.Millisecond
+)
+
+// Progress renders a single line progress bar on stderr, it is safe for concurrent use.
+// Nothing is rendered when stderr is not a terminal.
+type Progress struct {
+	mutex      sync.Mutex
+	label      string
+	total      uint64
+	done       int64
+	files      int
+	totalFiles int
+	rendered   time.Time
+	enabled    bool
+}
+
+func IsStderrTerminal() bool {
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+func NewProgress(label string, totalFiles int, total uint64) *Progress {
+	return &Progress{
+		label:      label,
+		total:      total,
+		totalFiles: totalFiles,
+		enabled:    IsStderrTerminal(),
+	}
+}
+
+// Add moves the bar by n bytes, negative values roll back a failed attempt
+func (p *Progress) Add(n int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done += n
+	p.render(false)
+}
+
+// Write counts the written bytes, so Progress can be used as a copy destination
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *Progress) FileDone() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.files++
+	p.render(true)
+}
+
+// Log prints a message above the bar
+func (p *Progress) Log(msg string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.enabled {
+		_, _ = fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	PrintErr(msg)
+	if p.enabled && !p.rendered.IsZero() {
+		p.render(true)
+	}
+}
+
+func (p *Progress) Finish() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.enabled && !p.rendered.IsZero() {
+		_, _ = fmt.Fprint(os.Stderr, "\n")
+	}
+	p.enabled = false
+}
+
+func (p *Progress) render(force bool) {
+	if !p.enabled || (!force && time.Since(p.rendered) < progressInterval) {
+		return
+	}
+	p.rendered = time.Now()
+
+	done := uint64(max(p.done, 0))
+	ratio := 1.0
+	if p.total > 0 {
+		ratio = min(float64(done)/float64(p.total), 1)
+	}
+	filled := int(ratio * progressWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressWidth-filled)
+
+	_, _ = fmt.Fprintf(
+		os.Stderr,
+		"\r\033[K%s [%s] %3.0f%% %s / %s (%d/%d files)",
+		p.label, bar, ratio*100, humanize.Bytes(done), humanize.Bytes(p.total), p.files, p.totalFiles,
+	)
+}
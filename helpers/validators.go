@@ -162,3 +162,12 @@ func GetValidValueArrayValidator(allowEmpty bool, itemValidator func(string) err
 		return nil
 	}
 }
+
+func GetEqualValidator(expected string) func(string) error {
+	return func(input string) error {
+		if input != expected {
+			return textinput.ErrInputValidation
+		}
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the ignore file read from the root of a watched folder
+const FileName = ".sherryignore"
+
+type rule struct {
+	negate  bool
+	dirOnly bool
+	regex   *regexp.Regexp
+}
+
+// Matcher matches slash separated paths relative to the folder root against gitignore style patterns
+type Matcher struct {
+	rules []rule
+}
+
+// compilePattern converts a single pattern to a regular expression, patterns with a slash
+// other than the trailing one are anchored to the root, others match at any depth
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Parse compiles the patterns, empty lines, comments and invalid patterns are skipped
+func Parse(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, line := range patterns {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		regex, err := compilePattern(line)
+		if err != nil {
+			continue
+		}
+		r.regex = regex
+		m.rules = append(m.rules, r)
+	}
+	return m
+}
+
+// ReadFile returns the lines of an ignore file, nothing if it does not exist
+func ReadFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Load combines the ignore file at the folder root with extra patterns, the extra ones take precedence
+func Load(root string, extra []string) (*Matcher, error) {
+	lines, err := ReadFile(filepath.Join(root, FileName))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(append(lines, extra...)), nil
+}
+
+func (m *Matcher) matchOne(p string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.regex.MatchString(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Match reports whether the path is ignored, a path inside an ignored directory is ignored as well
+func (m *Matcher) Match(p string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchOne(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchOne(strings.Join(parts, "/"), isDir)
+}
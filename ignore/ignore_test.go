@@ -0,0 +1,60 @@
+package ignore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m := Parse([]string{
+		"# build outputs",
+		"",
+		"*.tmp",
+		"build/",
+		"/root.txt",
+		"docs/*.bak",
+		"**/cache/**",
+		"~$*",
+		"*.log",
+		"!keep.log",
+		"\\#literal",
+		"file[0-9].txt",
+	})
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "a.tmp", want: true},
+		{path: "sub/dir/a.tmp", want: true},
+		{path: "a.txt", want: false},
+		{path: "build", isDir: true, want: true},
+		{path: "build", isDir: false, want: false},
+		{path: "build/out/a.bin", want: true},
+		{path: "sub/build/a.bin", want: true},
+		{path: "root.txt", want: true},
+		{path: "sub/root.txt", want: false},
+		{path: "docs/a.bak", want: true},
+		{path: "docs/sub/a.bak", want: false},
+		{path: "other/docs/a.bak", want: false},
+		{path: "a/cache/b/c", want: true},
+		{path: "cache/b", want: true},
+		{path: "~$report.docx", want: true},
+		{path: "error.log", want: true},
+		{path: "keep.log", want: false},
+		{path: "#literal", want: true},
+		{path: "file1.txt", want: true},
+		{path: "fileA.txt", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, m.Match(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestMatchEmpty(t *testing.T) {
+	var m *Matcher
+	assert.False(t, m.Match("a.txt", false))
+	assert.False(t, Parse(nil).Match("a.txt", false))
+}
@@ -1,21 +1,36 @@
 package main
 
 import (
+	"errors"
 	flag "github.com/jessevdk/go-flags"
+	"os"
 	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
 )
 
+func exit(err error) {
+	if err == nil {
+		return
+	}
+	if msg := err.Error(); msg != "" {
+		helpers.PrintErr(msg)
+	}
+	os.Exit(helpers.GetExitCode(err))
+}
+
 func main() {
 	var options Options
 	var parser = flag.NewParser(&options, flag.Default)
 
 	if _, err := parser.Parse(); err != nil {
-		return
+		var flagsErr *flag.Error
+		if errors.As(err, &flagsErr) && flagsErr.Type == flag.ErrHelp {
+			return
+		}
+		os.Exit(constants.ExitUsage)
 	}
 
-	c := config.SetupConfig(string(options.ConfigPath))
-	if c != nil {
-		return
-	}
-	applyCommand(parser.Command, options)
+	exit(config.SetupConfig(string(options.ConfigPath)))
+	exit(applyCommand(parser.Command, options))
 }
@@ -0,0 +1,180 @@
+package policy
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sherry/shr/config"
+	"strings"
+)
+
+type ViolationKind = string
+
+const (
+	ViolationFileSize  ViolationKind = "file-size"
+	ViolationDirSize   ViolationKind = "dir-size"
+	ViolationFileName  ViolationKind = "file-name"
+	ViolationFileType  ViolationKind = "file-type"
+	ViolationDirectory ViolationKind = "directory"
+)
+
+type Violation = struct {
+	Path    string        `json:"path"`
+	Kind    ViolationKind `json:"kind"`
+	Message string        `json:"message"`
+}
+
+// Rules are the restrictions of a shared folder, zero sizes and empty lists are not checked
+type Rules struct {
+	AllowDir         bool
+	MaxFileSize      uint64
+	MaxDirSize       uint64
+	AllowedFileNames []string
+	AllowedFileTypes []string
+}
+
+// File is an entry of the checked tree, Path is slash separated and relative to the folder root
+type File struct {
+	Path     string
+	Size     uint64
+	IsDir    bool
+	MimeType string
+}
+
+func FromSource(source config.Source) Rules {
+	return Rules{
+		AllowDir:         source.AllowDir,
+		MaxFileSize:      source.MaxFileSize,
+		MaxDirSize:       source.MaxDirSize,
+		AllowedFileNames: source.AllowedFileNames,
+		AllowedFileTypes: source.AllowedFileTypes,
+	}
+}
+
+// DetectMimeType guesses the MIME type by the file extension and falls back to sniffing the content
+func DetectMimeType(localPath string) (string, error) {
+	if t := mime.TypeByExtension(filepath.Ext(localPath)); t != "" {
+		return t, nil
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 512)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buffer[:n]), nil
+}
+
+// matchesName checks the file name against the globs, patterns with a slash are matched against the whole path
+func matchesName(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		target := path.Base(p)
+		if strings.Contains(pattern, "/") {
+			target = p
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func getMediaType(value string) string {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return strings.ToLower(value)
+	}
+	return mediaType
+}
+
+// matchesType checks the MIME type against the allowed types, "image/*" and "*/*" are accepted as wildcards
+func matchesType(types []string, mimeType string) bool {
+	actual := getMediaType(mimeType)
+	for _, t := range types {
+		allowed := getMediaType(t)
+		if allowed == "*/*" || allowed == actual {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(actual, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFile returns the violations of a single entry, it does not include the folder size
+func (r Rules) CheckFile(f File) []Violation {
+	var violations []Violation
+	add := func(kind ViolationKind, format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: f.Path, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !r.AllowDir && (f.IsDir || strings.Contains(f.Path, "/")) {
+		add(ViolationDirectory, "directories are not allowed in this folder")
+	}
+	if f.IsDir {
+		return violations
+	}
+	if r.MaxFileSize != 0 && f.Size > r.MaxFileSize {
+		add(ViolationFileSize, "%s exceeds max file size of %s", humanize.Bytes(f.Size), humanize.Bytes(r.MaxFileSize))
+	}
+	if len(r.AllowedFileNames) != 0 && !matchesName(r.AllowedFileNames, f.Path) {
+		add(ViolationFileName, "name does not match allowed names %s", strings.Join(r.AllowedFileNames, ", "))
+	}
+	if len(r.AllowedFileTypes) != 0 && !matchesType(r.AllowedFileTypes, f.MimeType) {
+		add(ViolationFileType, "type %s is not one of allowed types %s", getMediaType(f.MimeType), strings.Join(r.AllowedFileTypes, ", "))
+	}
+	return violations
+}
+
+// CheckLocalFile checks the file at localPath that would be uploaded as rel, the MIME type is detected only if needed
+func (r Rules) CheckLocalFile(localPath string, rel string) ([]Violation, error) {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+	f := File{Path: rel, Size: uint64(stat.Size()), IsDir: stat.IsDir()}
+	if len(r.AllowedFileTypes) != 0 && !f.IsDir {
+		if f.MimeType, err = DetectMimeType(localPath); err != nil {
+			return nil, err
+		}
+	}
+	return r.CheckFile(f), nil
+}
+
+func FormatViolations(violations []Violation) string {
+	var messages []string
+	for _, v := range violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Check returns the violations of every entry and of the total size of the folder
+func (r Rules) Check(files []File) []Violation {
+	var violations []Violation
+	var total uint64
+	for _, f := range files {
+		violations = append(violations, r.CheckFile(f)...)
+		total += f.Size
+	}
+	if r.MaxDirSize != 0 && total > r.MaxDirSize {
+		violations = append(violations, Violation{
+			Path:    ".",
+			Kind:    ViolationDirSize,
+			Message: fmt.Sprintf("total size %s exceeds max folder size of %s", humanize.Bytes(total), humanize.Bytes(r.MaxDirSize)),
+		})
+	}
+	return violations
+}
@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func kinds(violations []Violation) []ViolationKind {
+	var result []ViolationKind
+	for _, v := range violations {
+		result = append(result, v.Kind)
+	}
+	return result
+}
+
+func TestCheckFile(t *testing.T) {
+	rules := Rules{
+		AllowDir:         false,
+		MaxFileSize:      100,
+		AllowedFileNames: []string{"*.md", "docs/*.txt"},
+		AllowedFileTypes: []string{"text/*", "application/pdf"},
+	}
+	tests := []struct {
+		name string
+		file File
+		want []ViolationKind
+	}{
+		{name: "Test allowed file", file: File{Path: "readme.md", Size: 10, MimeType: "text/markdown; charset=utf-8"}},
+		{name: "Test oversize file", file: File{Path: "readme.md", Size: 101, MimeType: "text/markdown"}, want: []ViolationKind{ViolationFileSize}},
+		{name: "Test disallowed name", file: File{Path: "notes.txt", Size: 10, MimeType: "text/plain"}, want: []ViolationKind{ViolationFileName}},
+		{name: "Test disallowed type", file: File{Path: "image.md", Size: 10, MimeType: "image/png"}, want: []ViolationKind{ViolationFileType}},
+		{name: "Test nested file", file: File{Path: "docs/notes.txt", Size: 10, MimeType: "text/plain"}, want: []ViolationKind{ViolationDirectory}},
+		{name: "Test directory", file: File{Path: "docs", IsDir: true}, want: []ViolationKind{ViolationDirectory}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, kinds(rules.CheckFile(tt.file)))
+		})
+	}
+}
+
+func TestCheckWithoutRestrictions(t *testing.T) {
+	rules := Rules{AllowDir: true}
+	files := []File{
+		{Path: "a/b/c.bin", Size: 1e9, MimeType: "application/octet-stream"},
+		{Path: "a", IsDir: true},
+	}
+	assert.Empty(t, rules.Check(files))
+}
+
+func TestCheckDirSize(t *testing.T) {
+	rules := Rules{AllowDir: true, MaxDirSize: 100}
+	files := []File{
+		{Path: "a", Size: 60},
+		{Path: "b", Size: 60},
+	}
+	assert.Equal(t, []ViolationKind{ViolationDirSize}, kinds(rules.Check(files)))
+}
@@ -3,31 +3,53 @@ package service
 import (
 	flag "github.com/jessevdk/go-flags"
 	"sherry/shr/config"
+	"time"
 )
 
+const defaultStopTimeout = 10 * time.Second
+
 type Options struct {
-	Start StartOptions `command:"start" description:"start demon"`
-	Stop  StopOptions  `command:"stop" description:"stop the demon"`
+	Start     StartOptions     `command:"start" description:"start demon"`
+	Stop      StopOptions      `command:"stop" description:"stop the demon"`
+	Status    StatusOptions    `command:"status" description:"show the demon state"`
+	Install   InstallOptions   `command:"install" description:"install the demon as systemd user service"`
+	Uninstall UninstallOptions `command:"uninstall" description:"remove the demon systemd user service"`
 }
 
 type StartOptions struct {
 	Yes bool `short:"y" long:"yes" description:"yes to all"`
 }
-type StopOptions struct{}
+type InstallOptions struct {
+	Print bool `long:"print" description:"print the unit file to stdout instead of installing it"`
+}
+
+type UninstallOptions struct{}
+
+type StatusOptions struct{}
+
+type StopOptions struct {
+	Timeout time.Duration `short:"t" long:"timeout" default:"10s" description:"time to wait for graceful stop before killing the demon"`
+}
 
-func ApplyCommand(cmd *flag.Command, data Options) {
+func ApplyCommand(cmd *flag.Command, data Options) error {
 	if cmd.Active.Name != "service" {
-		return
+		return nil
 	}
 
-	config.WithCommit(func() bool {
+	return config.WithCommit(func() (bool, error) {
 		switch cmd.Active.Active.Name {
 		case "start":
 			return StartService(data.Start.Yes)
 		case "stop":
-			return StopService()
+			return StopService(data.Stop.Timeout)
+		case "install":
+			return InstallService(data.Install.Print)
+		case "uninstall":
+			return UninstallService()
+		case "status":
+			return StatusService()
 		default:
-			return false
+			return false, nil
 		}
 	})
 }
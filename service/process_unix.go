@@ -0,0 +1,46 @@
+//go:build !windows
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processExecutable returns the binary of the process, ok is false when procfs can't tell it
+func processExecutable(pid int) (string, bool) {
+	if _, err := os.Stat("/proc/self/exe"); err != nil {
+		return "", false
+	}
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ESRCH) {
+		// The process is gone or is a kernel thread, it is not the demon
+		return "", true
+	}
+	if err != nil {
+		// A demon of another user or procfs mounted with hidepid can't be checked, only its liveness is known
+		return "", false
+	}
+	// The demon keeps running from the replaced binary after an update
+	return strings.TrimSuffix(exe, " (deleted)"), true
+}
+
+func terminateProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+func killProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
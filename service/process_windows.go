@@ -0,0 +1,38 @@
+//go:build windows
+
+package service
+
+import (
+	"os"
+	"syscall"
+)
+
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func isProcessAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
+
+// processExecutable returns the binary of the process, ok is false when procfs can't tell it
+func processExecutable(pid int) (string, bool) {
+	return "", false
+}
+
+func terminateProcess(pid int) error {
+	return killProcess(pid)
+}
+
+func killProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -2,18 +2,24 @@ package service
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"sherry/shr/config"
 	"sherry/shr/helpers"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const startupDelay = time.Second
+const stopPollInterval = 100 * time.Millisecond
+
 func getServicePath() string {
 	return helpers.PreparePath(path.Join(config.GetConfigPath(), "bin", helpers.If(runtime.GOOS == "windows", func() string {
 		return "sherry-demon.exe"
@@ -26,6 +32,38 @@ func getPidPath() string {
 	return helpers.PreparePath(path.Join(config.GetConfigPath(), "pid"))
 }
 
+func getLogPath() string {
+	return helpers.PreparePath(path.Join(config.GetConfigPath(), "demon.log"))
+}
+
+// readPid returns the recorded pid, or 0 if the pid file is missing or empty
+func readPid() (int, error) {
+	data, err := os.ReadFile(getPidPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return 0, nil
+	}
+	pid, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %w", getPidPath(), err)
+	}
+	return pid, nil
+}
+
+func writePid(pid int) error {
+	return os.WriteFile(getPidPath(), []byte(strconv.Itoa(pid)), 0644)
+}
+
+func removePid() {
+	_ = os.Remove(getPidPath())
+}
+
 func getWindowsStartServiceCommand() []string {
 	ps, _ := exec.LookPath("powershell.exe")
 	servicePath := getServicePath()
@@ -46,29 +84,8 @@ exit
 	}
 }
 
-func StartService(yes bool) bool {
-	pid, e := os.ReadFile(getPidPath())
-	if e == nil && string(pid) != "" {
-		if yes {
-			StopService()
-		} else {
-			helpers.PrintErr(fmt.Sprintf("Service is already started, PID: %s", pid))
-			return false
-		}
-	}
-
-	servicePath := getServicePath()
-
-	helpers.PrintMessage(fmt.Sprintf("Starting service at %s", servicePath))
-
-	var args []string
-	switch runtime.GOOS {
-	case "windows":
-		args = getWindowsStartServiceCommand()
-	default:
-		helpers.PrintErr("not support")
-		return false
-	}
+func startWindowsService() (int, error) {
+	args := getWindowsStartServiceCommand()
 
 	var cmdOut, cmdErr bytes.Buffer
 	cmd := exec.Command(args[0], args[1:]...)
@@ -77,54 +94,215 @@ func StartService(yes bool) bool {
 	err := cmd.Start()
 
 	helpers.PrintMessage("Starting...")
-	time.Sleep(time.Second * 1)
+	time.Sleep(startupDelay)
 	if cmdErr.Len() > 0 {
-		helpers.PrintErr(cmdErr.String())
-		return false
+		return 0, errors.New(cmdErr.String())
 	}
 	if err != nil {
-		helpers.PrintErr(err.Error())
+		return 0, err
+	}
+
+	return strconv.Atoi(string(regexp.MustCompile("[0-9]+").Find(cmdOut.Bytes())))
+}
+
+func startLinuxService() (int, error) {
+	configPath := config.GetConfigPath()
+
+	logFile, err := os.OpenFile(getLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(getServicePath(), "-c", configPath, "-s")
+	cmd.Dir = configPath
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	helpers.PrintMessage("Starting...")
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("process exited")
+		}
+		return 0, fmt.Errorf("service stopped right after start: %s, see %s", err, getLogPath())
+	case <-time.After(startupDelay):
+		return cmd.Process.Pid, nil
+	}
+}
+
+// runsBinary reports whether the process is alive and runs the binary, the binary is not checked where it is unknown
+func runsBinary(pid int, binary string) bool {
+	if !isProcessAlive(pid) {
 		return false
 	}
+	exe, ok := processExecutable(pid)
+	if !ok {
+		return true
+	}
+	if resolved, err := filepath.EvalSymlinks(binary); err == nil {
+		binary = resolved
+	}
+	return exe == binary
+}
+
+// isServiceProcess reports whether the pid belongs to the running demon, a pid reused by another process is stale
+func isServiceProcess(pid int) bool {
+	return runsBinary(pid, getServicePath())
+}
+
+func stopWindowsService(pid int) error {
+	ps, _ := exec.LookPath("powershell.exe")
+	out, err := exec.Command(
+		ps, "-NoProfile", "-NonInteractive",
+		fmt.Sprintf(`kill %d`, pid),
+	).Output()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(out)) != "" {
+		helpers.PrintMessage(string(out))
+	}
+	return nil
+}
+
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !isServiceProcess(pid) {
+			return true
+		}
+		time.Sleep(stopPollInterval)
+	}
+	return !isServiceProcess(pid)
+}
+
+func stopLinuxService(pid int, timeout time.Duration) error {
+	if err := terminateProcess(pid); err != nil {
+		return err
+	}
+	if waitForExit(pid, timeout) {
+		return nil
+	}
 
-	pid = regexp.MustCompile("[0-9]+").Find(cmdOut.Bytes())
+	helpers.PrintMessage(fmt.Sprintf("Service did not stop in %s, killing it...", timeout))
+	if err := killProcess(pid); err != nil {
+		return err
+	}
+	if !waitForExit(pid, timeout) {
+		return fmt.Errorf("unable to stop process %d", pid)
+	}
+	return nil
+}
 
-	helpers.PrintMessage(fmt.Sprintf("The pid is %s", pid))
-	_ = os.WriteFile(getPidPath(), pid, 0644)
+// getRunningPid returns the pid of the running service, removing the pid file if the process is gone or is not the demon
+func getRunningPid() (int, error) {
+	pid, err := readPid()
+	if err != nil || pid == 0 {
+		return 0, err
+	}
+	if !isServiceProcess(pid) {
+		helpers.PrintMessage(fmt.Sprintf("Removing stale pid file, process %d is not the running demon", pid))
+		removePid()
+		return 0, nil
+	}
+	return pid, nil
+}
 
-	return false
+type Result = struct {
+	Action   string `json:"action"`
+	Pid      int    `json:"pid,omitempty"`
+	Binary   string `json:"binary,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	UnitPath string `json:"unitPath,omitempty"`
+	// Content is the unit file printed by install --print
+	Content string `json:"content,omitempty"`
 }
 
-func StopService() bool {
-	pid, err := os.ReadFile(getPidPath())
+func StartService(yes bool) (bool, error) {
+	pid, err := getRunningPid()
 	if err != nil {
-		helpers.PrintErr("Service is not started")
-		return false
+		return false, helpers.FailureError(err.Error())
+	}
+	if pid != 0 {
+		if !yes {
+			return false, helpers.ConflictError("Service is already started, PID: %d", pid)
+		}
+		if err := stopService(pid, defaultStopTimeout); err != nil {
+			return false, err
+		}
+		helpers.PrintMessage("Service stopped")
 	}
 
-	_ = os.Remove(getPidPath())
+	servicePath := getServicePath()
+
+	helpers.PrintMessage(fmt.Sprintf("Starting service at %s", servicePath))
 
-	var out []byte
 	switch runtime.GOOS {
 	case "windows":
-		ps, _ := exec.LookPath("powershell.exe")
-		out, err = exec.Command(
-			ps, "-NoProfile", "-NonInteractive",
-			fmt.Sprintf(`kill %s`, pid),
-		).Output()
+		pid, err = startWindowsService()
+	case "linux":
+		pid, err = startLinuxService()
 	default:
-		helpers.PrintErr("not support")
-		return false
+		return false, helpers.FailureError("not support")
 	}
 	if err != nil {
-		helpers.PrintErr(err.Error())
-		return false
+		return false, helpers.FailureError(err.Error())
 	}
 
-	if strings.TrimSpace(string(out)) != "" {
-		helpers.PrintMessage(string(out))
+	if err := writePid(pid); err != nil {
+		return false, helpers.FailureError(err.Error())
 	}
-	helpers.PrintMessage("Service stopped")
 
-	return false
+	helpers.PrintResult(Result{Action: "start", Pid: pid, Binary: servicePath}, func() {
+		helpers.PrintMessage(fmt.Sprintf("The pid is %d", pid))
+	})
+
+	return false, nil
+}
+
+func stopService(pid int, timeout time.Duration) error {
+	var err error
+	switch runtime.GOOS {
+	case "windows":
+		err = stopWindowsService(pid)
+	case "linux":
+		err = stopLinuxService(pid, timeout)
+	default:
+		return helpers.FailureError("not support")
+	}
+	if err != nil {
+		return helpers.FailureError(err.Error())
+	}
+
+	removePid()
+	return nil
+}
+
+func StopService(timeout time.Duration) (bool, error) {
+	pid, err := getRunningPid()
+	if err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+	if pid == 0 {
+		return false, helpers.FailureError("Service is not started")
+	}
+
+	if err := stopService(pid, timeout); err != nil {
+		return false, err
+	}
+
+	helpers.PrintResult(Result{Action: "stop", Pid: pid}, func() {
+		helpers.PrintMessage("Service stopped")
+	})
+
+	return false, nil
 }
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestRunsBinary(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the binary of a process is checked with procfs")
+	}
+	executable, err := os.Executable()
+	assert.NoError(t, err)
+
+	assert.True(t, runsBinary(os.Getpid(), executable))
+	assert.False(t, runsBinary(os.Getpid(), "/usr/bin/sherry-demon"))
+}
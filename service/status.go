@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"time"
+)
+
+type State = string
+
+const (
+	StateRunning State = "running"
+	StateStale   State = "stale"
+	StateStopped State = "stopped"
+)
+
+type Status struct {
+	State         State      `json:"state"`
+	Pid           int        `json:"pid,omitempty"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	UptimeSeconds int64      `json:"uptimeSeconds,omitempty"`
+	Binary        string     `json:"binary"`
+	BinaryExists  bool       `json:"binaryExists"`
+	ConfigPath    string     `json:"configPath"`
+	LogPath       string     `json:"logPath"`
+	Watchers      int        `json:"watchers"`
+	Complete      int        `json:"completeWatchers"`
+}
+
+func getStatus() (*Status, error) {
+	watchers := config.GetConfig().Watchers
+	status := Status{
+		State:        StateStopped,
+		Binary:       getServicePath(),
+		BinaryExists: helpers.IsExists(getServicePath()),
+		ConfigPath:   config.GetConfigPath(),
+		LogPath:      getLogPath(),
+		Watchers:     len(watchers),
+		Complete:     len(helpers.Filter(watchers, func(w config.Watcher) bool { return w.Complete })),
+	}
+
+	pid, err := readPid()
+	if err != nil {
+		return nil, err
+	}
+	if pid == 0 {
+		return &status, nil
+	}
+
+	status.Pid = pid
+	if !isServiceProcess(pid) {
+		status.State = StateStale
+		return &status, nil
+	}
+
+	status.State = StateRunning
+	// The pid file is written right after the demon is started
+	if stat, err := os.Stat(getPidPath()); err == nil {
+		startedAt := stat.ModTime()
+		status.StartedAt = &startedAt
+		status.UptimeSeconds = int64(time.Since(startedAt).Seconds())
+	}
+
+	return &status, nil
+}
+
+func getStatusExitCode(status *Status) int {
+	switch status.State {
+	case StateRunning:
+		return constants.ExitOk
+	case StateStale:
+		return constants.ExitServiceStale
+	default:
+		return constants.ExitServiceStopped
+	}
+}
+
+func printStatus(status *Status) {
+	switch status.State {
+	case StateRunning:
+		helpers.PrintMessage(fmt.Sprintf("Service:  %s (PID %d)", helpers.WithColor([]int{helpers.ConsoleFgDarkGreen}, status.State), status.Pid))
+	case StateStale:
+		helpers.PrintMessage(fmt.Sprintf("Service:  %s (PID %d is not the running demon)", helpers.WithColor([]int{helpers.ConsoleFgDarkRed}, status.State), status.Pid))
+	default:
+		helpers.PrintMessage(fmt.Sprintf("Service:  %s", helpers.WithColor([]int{helpers.ConsoleFgDarkYellow}, status.State)))
+	}
+	if status.StartedAt != nil {
+		uptime := time.Duration(status.UptimeSeconds) * time.Second
+		helpers.PrintMessage(fmt.Sprintf("Uptime:   %s (since %s)", uptime, status.StartedAt.Format(time.DateTime)))
+	}
+	binary := status.Binary
+	if !status.BinaryExists {
+		binary = fmt.Sprintf("%s (missing)", binary)
+	}
+	helpers.PrintMessage(fmt.Sprintf("Binary:   %s", binary))
+	helpers.PrintMessage(fmt.Sprintf("Config:   %s", status.ConfigPath))
+	helpers.PrintMessage(fmt.Sprintf("Log:      %s", status.LogPath))
+	helpers.PrintMessage(fmt.Sprintf("Watchers: %d (%d complete)", status.Watchers, status.Complete))
+}
+
+// StatusService prints the service state, the returned error carries the status exit code
+func StatusService() (bool, error) {
+	status, err := getStatus()
+	if err != nil {
+		return false, helpers.WrapError(constants.ExitServiceUnknown, err, fmt.Sprintf("Can't determine the service state: %s", err))
+	}
+
+	helpers.PrintResult(status, func() {
+		printStatus(status)
+	})
+
+	if code := getStatusExitCode(status); code != constants.ExitOk {
+		return false, helpers.NewError(code, "")
+	}
+	return false, nil
+}
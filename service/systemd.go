@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"sherry/shr/config"
+	"sherry/shr/constants"
+	"sherry/shr/helpers"
+	"sort"
+	"strings"
+)
+
+const systemdUnitName = "sherry-demon.service"
+
+// escapeSystemdArg quotes a value for use in ExecStart and Environment lines
+func escapeSystemdArg(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "%", "%%")
+	return fmt.Sprintf(`"%s"`, value)
+}
+
+// escapeSystemdPath escapes specifiers in unquoted path settings like WorkingDirectory
+func escapeSystemdPath(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+func renderSystemdUnit(binary string, configPath string, env map[string]string) string {
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=Sherry synchronization demon\n")
+	// A user unit cannot wait for network-online.target of the system manager, Restart covers the missing network
+	b.WriteString("\n")
+	b.WriteString("[Service]\n")
+	b.WriteString("Type=simple\n")
+	b.WriteString(fmt.Sprintf("ExecStart=%s -c %s -s\n", escapeSystemdArg(binary), escapeSystemdArg(configPath)))
+	b.WriteString(fmt.Sprintf("WorkingDirectory=%s\n", escapeSystemdPath(configPath)))
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf("Environment=%s\n", escapeSystemdArg(fmt.Sprintf("%s=%s", k, env[k]))))
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n")
+	b.WriteString("\n")
+	b.WriteString("[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+func getSystemdUnit() string {
+	configPath := config.GetConfigPath()
+	env := map[string]string{
+		constants.EnvConfigDir: configPath,
+	}
+	for _, k := range []string{constants.AnvApiUrl, constants.EnvSocketUrl} {
+		if v := os.Getenv(k); v != "" {
+			env[k] = v
+		}
+	}
+	return renderSystemdUnit(getServicePath(), configPath, env)
+}
+
+func getSystemdUnitPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = path.Join(home, ".config")
+	}
+	return path.Join(configHome, "systemd", "user", systemdUnitName), nil
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func InstallService(print bool) (bool, error) {
+	unit := getSystemdUnit()
+	if print {
+		helpers.PrintResult(Result{Action: "print", Unit: systemdUnitName, Content: unit}, func() {
+			fmt.Print(unit)
+		})
+		return false, nil
+	}
+
+	if runtime.GOOS != "linux" {
+		return false, helpers.FailureError("not support")
+	}
+
+	unitPath, err := getSystemdUnitPath()
+	if err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+
+	if pid, _ := readPid(); pid != 0 && isServiceProcess(pid) {
+		helpers.PrintMessage(fmt.Sprintf("Demon is already running with PID %d, stop it with \"shr service stop\" to avoid running it twice", pid))
+	}
+
+	if err := os.MkdirAll(path.Dir(unitPath), os.ModePerm); err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+	if err := os.WriteFile(unitPath, []byte(unit), 0644); err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+	helpers.PrintMessage(fmt.Sprintf("Unit written to %s", unitPath))
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+	if err := systemctl("enable", "--now", systemdUnitName); err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+
+	helpers.PrintResult(Result{Action: "install", Unit: systemdUnitName, UnitPath: unitPath}, func() {
+		helpers.PrintMessage(fmt.Sprintf("Service %s is enabled and started", systemdUnitName))
+	})
+
+	return false, nil
+}
+
+func UninstallService() (bool, error) {
+	if runtime.GOOS != "linux" {
+		return false, helpers.FailureError("not support")
+	}
+
+	unitPath, err := getSystemdUnitPath()
+	if err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+	if !helpers.IsExists(unitPath) {
+		return false, helpers.NotFoundError("Service is not installed")
+	}
+
+	if err := systemctl("disable", "--now", systemdUnitName); err != nil {
+		helpers.PrintErr(err.Error())
+	}
+	if err := os.Remove(unitPath); err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return false, helpers.FailureError(err.Error())
+	}
+
+	helpers.PrintResult(Result{Action: "uninstall", Unit: systemdUnitName, UnitPath: unitPath}, func() {
+		helpers.PrintMessage(fmt.Sprintf("Service %s is disabled and removed", systemdUnitName))
+	})
+
+	return false, nil
+}
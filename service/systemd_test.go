@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEscapeSystemdArg(t *testing.T) {
+	assert.Equal(t, `"/usr/bin/demon"`, escapeSystemdArg("/usr/bin/demon"))
+	assert.Equal(t, `"/home/a b/\"x\"\\y"`, escapeSystemdArg(`/home/a b/"x"\y`))
+	assert.Equal(t, `"/tmp/100%%"`, escapeSystemdArg("/tmp/100%"))
+}
+
+func TestRenderSystemdUnit(t *testing.T) {
+	unit := renderSystemdUnit("/home/user/.sherry/bin/sherry-demon", "/home/user/.sherry", map[string]string{
+		"SHERRY_CONFIG_PATH": "/home/user/.sherry",
+		"SHERRY_API_URL":     "http://localhost:3000",
+	})
+
+	assert.Equal(t, `[Unit]
+Description=Sherry synchronization demon
+
+[Service]
+Type=simple
+ExecStart="/home/user/.sherry/bin/sherry-demon" -c "/home/user/.sherry" -s
+WorkingDirectory=/home/user/.sherry
+Environment="SHERRY_API_URL=http://localhost:3000"
+Environment="SHERRY_CONFIG_PATH=/home/user/.sherry"
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`, unit)
+}